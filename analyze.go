@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+type jsonPackageCopies struct {
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	Folders        int    `json:"folders"`
+	Copies         int    `json:"copies"`
+	SizeBytes      int64  `json:"sizeBytes"`
+	TotalBytes     int64  `json:"totalBytes"`
+	DuplicateBytes int64  `json:"duplicateBytes"`
+}
+
+// runAnalyze reports the packages installed more than once across every
+// node_modules found, and what sharing one copy of each would save, and
+// returns the exit code.
+func runAnalyze(ctx context.Context, out io.Writer, c *Config, results *cleaner.Result) int {
+	var folders []*cleaner.Folder
+	folders = append(folders, results.Folders...)
+	folders = append(folders, results.Review...)
+	folders = append(folders, results.Dirty...)
+
+	packages, err := cleaner.AnalyzePackages(ctx, c.Options, folders)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		return ExitError
+	}
+
+	var duplicated []*cleaner.PackageCopies
+	var total, duplicate int64
+	for _, p := range packages {
+		if p.Copies > 1 {
+			duplicated = append(duplicated, p)
+			total += p.TotalBytes
+			duplicate += p.DuplicateBytes()
+		}
+	}
+
+	if c.json {
+		list := make([]jsonPackageCopies, 0, len(duplicated))
+		for _, p := range duplicated {
+			list = append(list, jsonPackageCopies{p.Name, p.Version, p.Folders, p.Copies, p.SizeBytes, p.TotalBytes, p.DuplicateBytes()})
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(list); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	if len(duplicated) == 0 {
+		_, _ = fmt.Fprintf(out, "No package is installed more than once in the %d folders found\n", len(folders))
+		return ExitOK
+	}
+
+	shown := duplicated
+	if c.analyzeTop > 0 && len(shown) > c.analyzeTop {
+		shown = shown[:c.analyzeTop]
+	}
+	_, _ = fmt.Fprintf(out, "Packages installed more than once, most space taken by extra copies first:\n")
+	for _, p := range shown {
+		copies := ""
+		if p.Copies > p.Folders {
+			copies = fmt.Sprintf(" as %d copies", p.Copies)
+		}
+		_, _ = fmt.Fprintf(out, "  %s@%s appears in %d projects%s, %s combined\n",
+			p.Name, p.Version, p.Folders, copies, cleaner.FormatSize(p.TotalBytes))
+	}
+	if len(shown) < len(duplicated) {
+		_, _ = fmt.Fprintf(out, "  and %d more\n", len(duplicated)-len(shown))
+	}
+
+	_, _ = fmt.Fprintf(out, "\n%d package versions are installed more than once across %d folders, taking %s, of which %s is extra copies.\n",
+		len(duplicated), len(folders), cleaner.FormatSize(total), cleaner.FormatSize(duplicate))
+	_, _ = fmt.Fprintf(out, "pnpm keeps one copy of each package version in a shared store, linked into each project, so switching these projects to it would save about %s.\n",
+		cleaner.FormatSize(duplicate))
+	return ExitOK
+}
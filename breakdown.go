@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// breakdowns sizes the largest packages in each of folders for -breakdown,
+// showing progress as it goes. A folder that can't be broken down has none.
+func breakdowns(ctx context.Context, c *Config, folders []*cleaner.Folder) map[*cleaner.Folder][]cleaner.PackageSize {
+	out := make(map[*cleaner.Folder][]cleaner.PackageSize, len(folders))
+	for i, f := range folders {
+		if ctx.Err() != nil {
+			break
+		}
+		c.Progress(fmt.Sprintf("Breaking down %d of %d: %s", i+1, len(folders), f.Path))
+		sizes, err := cleaner.Breakdown(ctx, c.Options, f.Path, cleaner.DefaultBreakdownTop)
+		if err == nil {
+			out[f] = sizes
+		}
+	}
+	c.Progress("")
+	return out
+}
+
+// printBreakdowns lists the largest packages in each folder, below the table.
+func printBreakdowns(w io.Writer, folders []*cleaner.Folder, sizes map[*cleaner.Folder][]cleaner.PackageSize) {
+	_, _ = fmt.Fprintf(w, "\nLargest packages:\n")
+	for _, f := range folders {
+		if len(sizes[f]) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s\n", f.Path)
+		for _, p := range sizes[f] {
+			_, _ = fmt.Fprintf(w, "  %12s  %s\n", cleaner.FormatSize(p.SizeBytes), p.Name)
+		}
+	}
+}
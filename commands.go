@@ -0,0 +1,415 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// command is a subcommand, with only the flags that make sense for it.
+type command struct {
+	name    string
+	summary string
+	flags   []func(fs *flag.FlagSet, c *Config)
+	// setup sets what the command implies, e.g. clean always deletes.
+	setup func(c *Config)
+	// args describes what may be listed after the flags, for commands that
+	// don't scan folders listed there.
+	args string
+}
+
+var commands = []*command{
+	{
+		name:    "scan",
+		summary: "list the folders that can be deleted",
+		flags:   []func(*flag.FlagSet, *Config){limitFlags, projectFlags, planFlags, outputFlags, tableFlags, recordFlags, notifyFlags, verbosityFlags, rootFlag},
+		setup:   func(c *Config) { c.delete = false },
+	},
+	{
+		name:    "clean",
+		summary: "find folders and delete them",
+		flags:   []func(*flag.FlagSet, *Config){limitFlags, projectFlags, planFlags, deleteFlags, machineFlags, tableFlags, recordFlags, notifyFlags, verbosityFlags, rootFlag},
+		setup:   func(c *Config) { c.delete = true },
+	},
+	{
+		name:    "report",
+		summary: "show the size distribution of every folder found",
+		flags:   []func(*flag.FlagSet, *Config){projectFlags, timeoutFlag, verbosityFlags, rootFlag},
+		setup:   func(c *Config) { c.Histogram = true },
+	},
+	{
+		name:    "caches",
+		summary: "list, or with -delete delete, the global npm, npx, yarn and pnpm caches",
+		flags:   []func(*flag.FlagSet, *Config){limitFlags, deleteFlag, deleteFlags, outputFlags, recordFlags, notifyFlags, verbosityFlags, rootFlag},
+		setup:   func(c *Config) { c.Caches = true },
+	},
+	{
+		name:    "analyze",
+		summary: "show the packages installed more than once across every node_modules found, whatever its age or size",
+		flags:   []func(*flag.FlagSet, *Config){projectFlags, timeoutFlag, analyzeFlags, verbosityFlags, rootFlag},
+		setup:   func(c *Config) { c.OlderThan, c.MinSize, c.MaxSize, c.Limit = 0, 0, 0, 0 },
+	},
+	{
+		name:    "projects",
+		summary: "list every project with a package.json, whether it has node_modules and their size, its package manager and when it was last modified",
+		flags:   []func(*flag.FlagSet, *Config){projectFlags, timeoutFlag, projectsFlags, verbosityFlags, rootFlag},
+	},
+	{
+		name:    "serve",
+		summary: "serve a JSON API to scan and delete remotely",
+		flags:   []func(*flag.FlagSet, *Config){limitFlags, projectFlags, deleteFlags, recordFlags, serveFlags, verbosityFlags, rootFlag},
+	},
+	{
+		name:    "history",
+		summary: "show past deletions and the space they reclaimed",
+		flags:   []func(*flag.FlagSet, *Config){historyFlags},
+	},
+	{
+		name:    "stats",
+		summary: "show how the space taken by the folders found changes from scan to scan",
+		flags:   []func(*flag.FlagSet, *Config){historyFlags},
+	},
+	{
+		name:    "restore",
+		summary: "reinstall the dependencies of projects whose node_modules were deleted, from their lockfiles",
+		flags:   []func(*flag.FlagSet, *Config){restoreFlags},
+		args:    "[project ...]",
+	},
+	{
+		name:    "config",
+		summary: "print the settings in effect, in the config file format",
+	},
+	{
+		name:    "schedule",
+		summary: "install, remove or show a recurring run",
+	},
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// defineFlags defines every flag on fs, for running without a command and
+// for the config file and environment variables, which apply to all commands.
+func defineFlags(fs *flag.FlagSet, c *Config) {
+	limitFlags(fs, c)
+	projectFlags(fs, c)
+	planFlags(fs, c)
+	deleteFlag(fs, c)
+	deleteFlags(fs, c)
+	outputFlags(fs, c)
+	tableFlags(fs, c)
+	recordFlags(fs, c)
+	notifyFlags(fs, c)
+	serveFlags(fs, c)
+	verbosityFlags(fs, c)
+	rootFlag(fs, c)
+	fs.BoolVar(&c.Histogram, "histogram", c.Histogram, "print the size distribution of every node_modules folder found instead of the candidates")
+	fs.BoolVar(&c.Caches, "caches", c.Caches, "look at the global npm, npx, yarn and pnpm caches instead of project folders")
+	fs.BoolVar(&c.watch, "watch", c.watch, "keep running, maintaining an index of found folders from filesystem notifications for -use-index")
+}
+
+// limitFlags are the age and size limits and ordering, shared by project
+// folders and caches.
+func limitFlags(fs *flag.FlagSet, c *Config) {
+	fs.Var((*ageFlag)(&c.OlderThan), "older", "only include projects with no file modified within this long, e.g. 30d, 2w or 12h, a number on its own is in days")
+	fs.Var((*sizeFlag)(&c.MinSize), "min-size", "only include folders of at least this size, e.g. 500MB or 1.5GB")
+	fs.Var((*sizeFlag)(&c.MinSize), "mbthresh", "same as -min-size, kept for compatibility")
+	fs.BoolVar(&c.DiskUsage, "disk-usage", c.DiskUsage, "size folders by the disk space their files take up, rather than by adding up file sizes")
+	fs.Var((*sizeFlag)(&c.MaxSize), "max-size", "folders larger than this size are listed for review and never deleted, 0 for no limit")
+	fs.IntVar(&c.Limit, "limit", c.Limit, "only include the first this many folders in -sort order, 0 for no limit")
+	fs.StringVar(&c.SortBy, "sort", c.SortBy, "order to list folders in: score (best to delete first, see -score-weights), size (largest first), age (oldest first) or path")
+	fs.Var((*scoreWeightsFlag)(&c.ScoreWeights), "score-weights", "how much the folder's size, its own age, the project's last modified file and its last git commit count towards the score, e.g. size=1,age=1,activity=2,git=2")
+	fs.BoolVar(&c.Reverse, "reverse", c.Reverse, "reverse the -sort order")
+	timeoutFlag(fs, c)
+	fs.StringVar(&c.failOn, "fail-on", c.failOn, "comma separated conditions that give a nonzero exit code: found (folders found in a run without -delete, exit 2), delete-error (exit 3), scan-error (folders that couldn't be read, exit 4) or none")
+}
+
+// projectFlags choose where and how project folders are found.
+func projectFlags(fs *flag.FlagSet, c *Config) {
+	fs.Var(&c.fromDirs, "from", "folder to start scanning from, can be given more than once, and folders can also be listed after the flags")
+	fs.BoolVar(&c.Exact, "exact", c.Exact, "look at only the folders listed after the flags, each a target folder or a project, whatever their age or size, instead of scanning from them")
+	fs.BoolVar(&c.stdin, "stdin", c.stdin, "look at the folders read from stdin, one to a line or NUL separated, instead of scanning")
+	fs.BoolVar(&c.PerUser, "per-user", c.PerUser, "scan each user's home folder, in /home, /Users or C:\\Users, instead of -from, with a total for each user")
+	fs.BoolVar(&c.AllDrives, "all-drives", c.AllDrives, "on Windows, scan every fixed drive instead of -from")
+	fs.DurationVar(&c.MaxDuration, "max-duration", c.MaxDuration, "stop looking for folders after this long, e.g. 5m, and go on with those found so far, marked as partial, 0 for no limit")
+	fs.IntVar(&c.MaxDepth, "max-depth", c.MaxDepth, "only look for projects up to this many folders below the start folder, 0 for no limit")
+	fs.BoolVar(&c.FollowSymlinks, "follow-symlinks", c.FollowSymlinks, "walk into links to folders, such as a projects folder linked from another volume, scanning each folder only once however many links lead to it")
+	fs.BoolVar(&c.OneFileSystem, "one-file-system", c.OneFileSystem, "don't scan other filesystems mounted below -from, such as external drives and network shares")
+	fs.BoolVar(&c.IncludeRemote, "include-remote", c.IncludeRemote, "scan network shares and cloud storage folders such as OneDrive and Dropbox, which are skipped by default")
+	fs.BoolVar(&c.IncludeWindowsDrives, "include-windows-drives", c.IncludeWindowsDrives, "when running in WSL, scan Windows drives mounted below -from such as /mnt/c, which are skipped by default")
+	fs.BoolVar(&c.IncludeWSL, "include-wsl", c.IncludeWSL, "on Windows, also scan the home folders of WSL distributions under \\\\wsl$")
+	fs.BoolVar(&c.IncludeLibrary, "include-library", c.IncludeLibrary, "on macOS, scan Library folders, Time Machine snapshots and backups, and app bundles, which are skipped by default")
+	fs.BoolVar(&c.SkipHidden, "skip-hidden", c.SkipHidden, "skip hidden (dot-prefixed) folders and everything below them")
+	fs.Var((*invertedBool)(&c.SkipHidden), "include-hidden", "scan hidden (dot-prefixed) folders, the opposite of -skip-hidden")
+	fs.Var(&c.excludePatterns, "exclude", "skip folders matching this glob or path prefix, can be given more than once")
+	fs.Var(&c.targetNames, "target", "folder name to look for, can be given more than once (default node_modules)")
+	fs.Var(&c.presets, "preset", "look for the build folders of an ecosystem: "+cleaner.PresetNames()+", can be given more than once (default npm)")
+	fs.BoolVar(&c.CachesOnly, "caches-only", c.CachesOnly, "in each node_modules folder found, only include the build tool caches, browsers downloaded by puppeteer and playwright, and node-gyp's intermediate files, leaving the install working")
+	fs.BoolVar(&c.buildArtifacts, "build-artifacts", c.buildArtifacts, "also look for the build output of JavaScript projects: dist, build, .next, .nuxt, .turbo, .parcel-cache, coverage and storybook-static folders beside a package.json, the same as adding -preset "+cleaner.ArtifactsPreset)
+	fs.Var((*sizeFlag)(&c.ArtifactMinSize), "build-min-size", "only include build output folders of at least this size, 0 for the same as -min-size")
+	fs.Var((*ageFlag)(&c.ArtifactOlderThan), "build-older", "only include build output folders in projects with no file modified within this long, 0 for the same as -older")
+	fs.StringVar(&c.AgeSource, "age-source", c.AgeSource, "how a project's age is worked out: project (newest file in the project), target (the found folder's own modified time) or git (the last commit)")
+	fs.BoolVar(&c.SkipDirty, "skip-dirty", c.SkipDirty, "skip projects that are git repos with uncommitted or unpushed changes")
+	fs.BoolVar(&c.SplitWorkspaces, "split-workspaces", c.SplitWorkspaces, "list the folders of npm, yarn and pnpm workspace packages on their own, rather than together with the workspace root's")
+	fs.BoolVar(&c.OrphansOnly, "orphans-only", c.OrphansOnly, "only include folders whose project has no package.json, whatever their age or size")
+	fs.Var((*sizeFlag)(&c.FreeGoal), "free", "scan everything and only include the largest folders needed to free this much space, e.g. 20GB")
+	fs.IntVar(&c.KeepRecent, "keep-recent", c.KeepRecent, "scan everything and include all but the most recently modified N projects, whatever their age or size")
+	fs.IntVar(&c.Workers, "workers", c.Workers, "number of folders to scan at once")
+	fs.BoolVar(&c.nice, "nice", c.nice, "run at idle disk and CPU priority, scanning and deleting one folder at a time, so a background cleanup doesn't slow down anything else")
+	fs.BoolVar(&c.SizeCache, "size-cache", c.SizeCache, "remember folder sizes between runs, only re-sizing folders that have changed")
+	fs.BoolVar(&c.UseIndex, "use-index", c.UseIndex, "use the index kept by -watch instead of scanning")
+	fs.BoolVar(&c.forceUnlock, "force-unlock", c.forceUnlock, "run even if another run seems to be scanning the same folders, breaking its lock")
+}
+
+func deleteFlag(fs *flag.FlagSet, c *Config) {
+	fs.BoolVar(&c.delete, "delete", c.delete, "set to delete found folders")
+}
+
+// deleteFlags control how folders are deleted.
+func deleteFlags(fs *flag.FlagSet, c *Config) {
+	fs.Var((*sizeFlag)(&c.confirmOver), "confirm-over", "require typing the folder count or DELETE when deleting more than this size in total, 0 to disable")
+	fs.BoolVar(&c.interactive, "interactive", c.interactive, "choose which found folders to delete before deleting them")
+	fs.BoolVar(&c.confirmEach, "confirm-each", c.confirmEach, "ask about each folder in turn before deleting: yes, skip, all or quit")
+	fs.BoolVar(&c.Trash, "trash", c.Trash, "move deleted folders to the trash or recycle bin instead of removing them")
+	fs.BoolVar(&c.yes, "yes", c.yes, "answer yes to all confirmation prompts")
+	fs.IntVar(&c.VerifyRetries, "verify-retries", c.VerifyRetries, "times to retry deleting a folder that still exists after being deleted")
+	fs.IntVar(&c.LockRetries, "lock-retries", c.LockRetries, "times to retry, waiting longer each time, deleting a folder with files locked by another program")
+	fs.StringVar(&c.DeleteOrder, "delete-order", c.DeleteOrder, "order to start deleting folders in: largest, smallest or oldest")
+	fs.IntVar(&c.DeleteWorkers, "delete-workers", c.DeleteWorkers, "number of folders to delete at once")
+	archiveFlag(fs, c)
+	fs.StringVar(&c.ArchiveCompress, "archive-compress", c.ArchiveCompress, "how to compress -archive archives: none, gzip, or zstd, which needs the zstd command")
+	fs.BoolVar(&c.rollbackPending, "rollback-pending", c.rollbackPending, "put back the folders an interrupted run was deleting, instead of scanning")
+}
+
+// recordFlags choose what is kept about each run once it is over.
+func recordFlags(fs *flag.FlagSet, c *Config) {
+	fs.BoolVar(&c.history, "history", c.history, "record scans and deleted folders for the history, stats and restore commands")
+	fs.StringVar(&c.logFile, "log-file", c.logFile, "append a record of each folder found, skipped, sized and deleted, and why, to this file")
+	fs.StringVar(&c.logFormat, "log-format", c.logFormat, "format of -log-file records: json or logfmt")
+	fs.StringVar(&c.metricsFile, "metrics-file", c.metricsFile, "write Prometheus metrics for each run to this file, for node_exporter's textfile collector")
+}
+
+// notifyFlags tell someone when a run is over.
+func notifyFlags(fs *flag.FlagSet, c *Config) {
+	fs.BoolVar(&c.notify, "notify", c.notify, "show a desktop notification summarising what was found or deleted when the run is over")
+	fs.BoolVar(&c.notifyUsers, "notify-users", c.notifyUsers, "with -per-user, write each user a summary of their folders found or deleted to "+userSummaryFile+" in their home folder")
+	fs.StringVar(&c.notifyURL, "notify-url", c.notifyURL, "post a JSON summary of the run to this webhook, such as a Slack or Teams incoming webhook, when it is over")
+}
+
+// serveFlags are where the serve command listens and the token clients must
+// send.
+func serveFlags(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.addr, "addr", c.addr, "address for serve to listen on, e.g. :8080 for every interface")
+	fs.StringVar(&c.token, "token", c.token, "token serve clients must send as \"Authorization: Bearer <token>\", better set with NPMCLEANER_TOKEN than on the command line")
+}
+
+// historyFlags are the history and stats commands' own flags.
+func historyFlags(fs *flag.FlagSet, c *Config) {
+	fs.Var((*ageFlag)(&c.historySince), "since", "only include runs within this long, e.g. 180d or 26w, 0 for all")
+	fs.BoolVar(&c.json, "json", c.json, "print JSON instead of a table")
+}
+
+func archiveFlag(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.ArchiveDir, "archive", c.ArchiveDir, "write each folder to a tar archive in this folder before deleting it, for the restore command to extract")
+}
+
+// analyzeFlags are the analyze command's own flags.
+func analyzeFlags(fs *flag.FlagSet, c *Config) {
+	fs.IntVar(&c.analyzeTop, "top", c.analyzeTop, "list this many packages, those with the most space in extra copies first, 0 for all")
+	fs.BoolVar(&c.json, "json", c.json, "print every package installed more than once as JSON")
+}
+
+// projectsFlags are the projects command's own flags.
+func projectsFlags(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.SortBy, "sort", c.SortBy, "order to list projects in: size or score (largest node_modules first), age (least recently modified first) or path")
+	fs.BoolVar(&c.Reverse, "reverse", c.Reverse, "reverse the -sort order")
+	fs.BoolVar(&c.json, "json", c.json, "print every project as JSON")
+}
+
+// restoreFlags are the restore command's own flags.
+func restoreFlags(fs *flag.FlagSet, c *Config) {
+	archiveFlag(fs, c)
+	fs.BoolVar(&c.restorePrint, "print", c.restorePrint, "print the commands as a script instead of running them")
+}
+
+func outputFlags(fs *flag.FlagSet, c *Config) {
+	machineFlags(fs, c)
+	fs.StringVar(&c.format, "format", c.format, "how to print the folders found: table, json (same as -json), md for Markdown tables to paste into an issue or wiki, or csv or tsv for a spreadsheet, the last three never deleting")
+	fs.BoolVar(&c.print0, "print0", c.print0, "print only the paths of found folders, each followed by a NUL byte, and never delete")
+	fs.BoolVar(&c.print0, "paths-only", c.print0, "same as -print0")
+}
+
+// planFlags save the folders found to a plan file and load them back, so a
+// slow scan can be reviewed before deleting without scanning again.
+func planFlags(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.planOut, "o", c.planOut, "save the folders found to this plan file, which can be edited and then deleted with clean -from-plan")
+	fs.StringVar(&c.fromPlan, "from-plan", c.fromPlan, "look at only the folders in this plan file saved with -o, instead of scanning, skipping any whose project has been modified since")
+	fs.StringVar(&c.reportOut, "report", c.reportOut, "also write the folders found to this standalone HTML file, with a chart of where the space is")
+}
+
+// tableFlags add to what is shown about each folder found.
+func tableFlags(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.pathDisplay, "path-display", c.pathDisplay, "how to show paths in the table: full, relative to the start folder, or home with the home folder as ~")
+	fs.StringVar(&c.color, "color", c.color, "color the table: auto (when printing to a terminal, unless NO_COLOR is set), always or never")
+	fs.BoolVar(&c.breakdown, "breakdown", c.breakdown, "list the 10 largest packages in each folder, such as a bundled browser or a build cache")
+	fs.IntVar(&c.groupDepth, "group-depth", c.groupDepth, "list the total for each folder this many levels below the start folder rather than each folder found, and choose whole groups with -interactive")
+}
+
+// machineFlags are the output formats for scripts.
+func machineFlags(fs *flag.FlagSet, c *Config) {
+	fs.BoolVar(&c.json, "json", c.json, "print results as JSON instead of a table")
+	fs.BoolVar(&c.porcelain, "porcelain", c.porcelain, "print one line per folder in a stable format for scripts: version, status, size in bytes, age in seconds and quoted path")
+}
+
+func timeoutFlag(fs *flag.FlagSet, c *Config) {
+	fs.DurationVar(&c.timeout, "timeout", c.timeout, "stop after this long, e.g. 10m, showing what was found or deleted so far, 0 for no limit")
+}
+
+// verbosityFlags choose how much is printed besides the results.
+func verbosityFlags(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.verbosity, "verbosity", c.verbosity, "how much to print besides the results: quiet, normal (progress and warnings), verbose (why each folder is skipped, as it is) or trace (every folder visited)")
+	fs.Var(&levelFlag{&c.verbosity, VerbosityQuiet}, "q", "same as -verbosity quiet")
+	fs.Var(&levelFlag{&c.verbosity, VerbosityVerbose}, "v", "same as -verbosity verbose")
+	fs.Var(&levelFlag{&c.verbosity, VerbosityTrace}, "vv", "same as -verbosity trace")
+}
+
+// rootFlag is needed to run as root, for every command that scans.
+func rootFlag(fs *flag.FlagSet, c *Config) {
+	fs.BoolVar(&c.allowRoot, "allow-root", c.allowRoot, "run as root, which can read and delete every user's folders")
+}
+
+// parseCommand parses the flags for cmd from args into c.
+func parseCommand(cmd *command, c *Config, args []string) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	for _, define := range cmd.flags {
+		define(fs, c)
+	}
+	fs.Usage = func() {
+		out := fs.Output()
+		args := ""
+		if fs.Lookup("from") != nil {
+			args = " [folder ...]"
+		} else if cmd.args != "" {
+			args = " " + cmd.args
+		}
+		_, _ = fmt.Fprintf(out, "usage: npm-cleaner %s [flags]%s\n\n%s.\n", cmd.name, args, capitalize(cmd.summary))
+		if len(cmd.flags) > 0 {
+			_, _ = fmt.Fprintf(out, "\nflags:\n")
+			fs.PrintDefaults()
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 && fs.Lookup("from") == nil && cmd.args == "" {
+		return fmt.Errorf("unexpected argument %q for %s", fs.Arg(0), cmd.name)
+	}
+	c.args = fs.Args()
+
+	c.command = cmd.name
+	if cmd.setup != nil {
+		cmd.setup(c)
+	}
+	return nil
+}
+
+// usage prints the commands and then every flag, which can still be given
+// without a command.
+func usage() {
+	out := flag.CommandLine.Output()
+	_, _ = fmt.Fprintf(out, "usage: npm-cleaner [command] [flags]\n\ncommands:\n")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(out, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	_, _ = fmt.Fprintf(out, "\nRun npm-cleaner <command> -h for a command's flags. With no command every\nflag is accepted:\n")
+	flag.PrintDefaults()
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// flagAliases are flags left out of the config command's output as they set
+// the same thing as another flag.
+var flagAliases = map[string]bool{
+	"mbthresh":       true,
+	"paths-only":     true,
+	"include-hidden": true,
+	"q":              true,
+	"v":              true,
+	"vv":             true,
+}
+
+// secretFlags are flags whose values the config command doesn't show, only
+// whether they are set, so its output can be shared safely.
+var secretFlags = map[string]bool{
+	"token": true,
+}
+
+// printConfig writes the value of every flag in fs, after the config file and
+// environment variables are applied, in the config file format.
+func printConfig(out io.Writer, fs *flag.FlagSet) error {
+	p, err := configFilePath()
+	if err != nil {
+		p = "none"
+	}
+	if _, err := os.Stat(p); err != nil {
+		p += " (not found)"
+	}
+	_, _ = fmt.Fprintf(out, "# Config file: %s\n", p)
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if flagAliases[f.Name] {
+			return
+		}
+		value := configValue(f.Value)
+		if secretFlags[f.Name] && f.Value.String() != "" {
+			value = strconv.Quote("REDACTED")
+		}
+		_, _ = fmt.Fprintf(out, "%s = %s\n", strings.ReplaceAll(f.Name, "-", "_"), value)
+	})
+	return nil
+}
+
+// configValue formats v as a config file value: booleans and numbers as they
+// are, repeatable flags as an array and everything else as a string.
+func configValue(v flag.Value) string {
+	var list []string
+	switch v := v.(type) {
+	case *stringList:
+		list = *v
+	case *dirList:
+		list = v.values()
+	}
+	if list != nil {
+		quoted := make([]string, len(list))
+		for i, s := range list {
+			quoted[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+
+	s := v.String()
+	if b, ok := v.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return s
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return s
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestPrintConfigRedactsToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{token: "s3cret", want: `token = "REDACTED"`},
+		{token: "", want: `token = ""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			t.Setenv("NPMCLEANER_TOKEN", tt.token)
+			fs := flag.NewFlagSet("npm-cleaner", flag.ContinueOnError)
+			defineFlags(fs, newConfig())
+			if err := applyEnv(fs); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := printConfig(&out, fs); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(out.String(), "\n")
+			if !contains(lines, tt.want) {
+				t.Errorf("output has no line %q:\n%s", tt.want, out.String())
+			}
+			if tt.token != "" && strings.Contains(out.String(), tt.token) {
+				t.Errorf("output shows the token:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
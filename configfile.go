@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const ConfigFileEnv = "NPMCLEANER_CONFIG"
+
+// configFilePath returns the config file to load, NPMCLEANER_CONFIG if set
+// or npm-cleaner/config.toml in the user's config folder.
+func configFilePath() (string, error) {
+	if p := os.Getenv(ConfigFileEnv); p != "" {
+		return p, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "npm-cleaner", "config.toml"), nil
+}
+
+// applyConfigFile sets flags from the config file, if there is one. It must be
+// called before applyEnv and flags.Parse so both take precedence.
+func applyConfigFile(flags *flag.FlagSet) error {
+	p, err := configFilePath()
+	if err != nil {
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) && os.Getenv(ConfigFileEnv) == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := parseConfig(f, flags); err != nil {
+		return fmt.Errorf("%s: %w", p, err)
+	}
+	return nil
+}
+
+// parseConfig reads a small subset of TOML: top level "key = value" pairs
+// where the key is a flag name (with - or _) and the value is a string,
+// number, boolean or single-line array of strings. Arrays set the flag once
+// per element.
+func parseConfig(r io.Reader, flags *flag.FlagSet) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		eq := strings.Index(text, "=")
+		if eq < 0 {
+			return fmt.Errorf("line %d: expected key = value", line)
+		}
+
+		key := strings.ReplaceAll(strings.TrimSpace(text[:eq]), "_", "-")
+		if flags.Lookup(key) == nil {
+			return fmt.Errorf("line %d: unknown setting %q", line, key)
+		}
+
+		values, err := parseConfigValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		for _, v := range values {
+			if err := flags.Set(key, v); err != nil {
+				return fmt.Errorf("line %d: invalid value %q for %s: %w", line, v, key, err)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+func parseConfigValue(v string) ([]string, error) {
+	if strings.HasPrefix(v, "[") {
+		if !strings.HasSuffix(v, "]") {
+			return nil, fmt.Errorf("arrays must be on a single line")
+		}
+
+		var values []string
+		for _, item := range splitConfigArray(v[1 : len(v)-1]) {
+			s, err := parseConfigScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+
+	s, err := parseConfigScalar(v)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+// splitConfigArray splits the inside of an array on commas that are not
+// within a quoted string.
+func splitConfigArray(v string) []string {
+	var items []string
+	var quote rune
+	start := 0
+	for i, ch := range v {
+		switch {
+		case quote != 0 && ch == quote && (quote == '\'' || i == 0 || v[i-1] != '\\'):
+			quote = 0
+		case quote == 0 && (ch == '"' || ch == '\''):
+			quote = ch
+		case quote == 0 && ch == ',':
+			items = append(items, v[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, v[start:])
+
+	trimmed := items[:0]
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			trimmed = append(trimmed, item)
+		}
+	}
+	return trimmed
+}
+
+func parseConfigScalar(v string) (string, error) {
+	if i := strings.Index(v, " #"); i >= 0 && !strings.HasPrefix(v, "\"") && !strings.HasPrefix(v, "'") {
+		v = strings.TrimSpace(v[:i])
+	}
+
+	switch {
+	case strings.HasPrefix(v, "'"):
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string %s", v)
+		}
+		return v[1 : end+1], nil
+	case strings.HasPrefix(v, "\""):
+		s, err := strconv.QuotedPrefix(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", v)
+		}
+		return strconv.Unquote(s)
+	case v == "":
+		return "", fmt.Errorf("missing value")
+	}
+
+	return v, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// loadSettings applies the config file holding file, if not empty, then the
+// environment variables in env, then the scan command's args, as main does.
+func loadSettings(t *testing.T, file string, env map[string]string, args []string) *Config {
+	t.Helper()
+	if file != "" {
+		p := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(p, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(ConfigFileEnv, p)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	c := newConfig()
+	fs := flag.NewFlagSet("npm-cleaner", flag.ContinueOnError)
+	defineFlags(fs, c)
+	if err := applyConfigFile(fs); err != nil {
+		t.Fatal(err)
+	}
+	c.fromDirs.override()
+	if err := applyEnv(fs); err != nil {
+		t.Fatal(err)
+	}
+	c.fromDirs.override()
+	if err := parseCommand(findCommand("scan"), c, args); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSettingsPrecedence(t *testing.T) {
+	size := func(s string) int64 {
+		n, err := cleaner.ParseSize(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		env         map[string]string
+		args        []string
+		wantMaxSize int64
+		wantFrom    []string
+		wantExclude []string
+	}{
+		{
+			name:        "defaults",
+			wantMaxSize: newConfig().MaxSize,
+			wantFrom:    newConfig().fromDirs.values(),
+		},
+		{
+			name:        "config file",
+			file:        "max_size = \"1GB\"\nfrom = [\"/a\", \"/b\"]\nexclude = [\"x\"]\n",
+			wantMaxSize: size("1GB"),
+			wantFrom:    []string{"/a", "/b"},
+			wantExclude: []string{"x"},
+		},
+		{
+			name:        "environment over config file",
+			file:        "max_size = \"1GB\"\nfrom = [\"/a\", \"/b\"]\nexclude = [\"x\"]\n",
+			env:         map[string]string{"NPMCLEANER_MAX_SIZE": "2GB", "NPMCLEANER_FROM": "/c", "NPMCLEANER_EXCLUDE": "y"},
+			wantMaxSize: size("2GB"),
+			wantFrom:    []string{"/c"},
+			wantExclude: []string{"x", "y"},
+		},
+		{
+			name:        "flags over environment and config file",
+			file:        "max_size = \"1GB\"\nfrom = [\"/a\", \"/b\"]\nexclude = [\"x\"]\n",
+			env:         map[string]string{"NPMCLEANER_MAX_SIZE": "2GB", "NPMCLEANER_FROM": "/c", "NPMCLEANER_EXCLUDE": "y"},
+			args:        []string{"-max-size", "3GB", "-from", "/d", "-exclude", "z"},
+			wantMaxSize: size("3GB"),
+			wantFrom:    []string{"/d"},
+			wantExclude: []string{"x", "y", "z"},
+		},
+		{
+			name:        "flags over config file",
+			file:        "max_size = \"1GB\"\nfrom = [\"/a\", \"/b\"]\n",
+			args:        []string{"-from", "/d", "-from", "/e"},
+			wantMaxSize: size("1GB"),
+			wantFrom:    []string{"/d", "/e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := loadSettings(t, tt.file, tt.env, tt.args)
+			if c.MaxSize != tt.wantMaxSize {
+				t.Errorf("max size = %d, want %d", c.MaxSize, tt.wantMaxSize)
+			}
+			if got := c.fromDirs.values(); !reflect.DeepEqual(got, tt.wantFrom) {
+				t.Errorf("from = %q, want %q", got, tt.wantFrom)
+			}
+			if got := []string(c.excludePatterns); !reflect.DeepEqual(got, tt.wantExclude) {
+				t.Errorf("exclude = %q, want %q", got, tt.wantExclude)
+			}
+		})
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{name: "unknown setting", file: "no_such_flag = 1\n"},
+		{name: "not key = value", file: "max_size\n"},
+		{name: "invalid value", file: "max_size = \"lots\"\n"},
+		{name: "array over several lines", file: "from = [\"/a\",\n\"/b\"]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(p, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(ConfigFileEnv, p)
+
+			fs := flag.NewFlagSet("npm-cleaner", flag.ContinueOnError)
+			defineFlags(fs, newConfig())
+			if err := applyConfigFile(fs); err == nil {
+				t.Errorf("no error for %q", tt.file)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+var errNotInteractive = errors.New("confirmation required but stdin is not a terminal, run with -yes to skip")
+
+// chooseToDelete asks which folders to delete with -interactive and
+// -confirm-each, narrowing results to them, then confirms as confirmDelete
+// does. Prompts, and why nothing is to be deleted, are written to out. It
+// reports whether to go ahead and delete what is left in results.
+func chooseToDelete(in io.Reader, out io.Writer, results *cleaner.Result, c *Config) (bool, error) {
+	if c.interactive {
+		if !isInteractive(in) {
+			return false, errNotInteractive
+		}
+
+		var chosen []*cleaner.Folder
+		var err error
+		if c.groupDepth > 0 {
+			groups, members := groupFolders(results.Folders, c.startDirs(), c.groupDepth, c.SortBy, c.Reverse)
+			chosen, err = selectFolders(in, out, groups)
+			chosen = ungroup(chosen, members)
+		} else {
+			chosen, err = selectFolders(in, out, results.Folders)
+		}
+		if err != nil {
+			return false, err
+		}
+		if len(chosen) == 0 {
+			_, _ = fmt.Fprintf(out, "Nothing selected, nothing deleted\n")
+			return false, nil
+		}
+		results.Keep(chosen)
+	}
+
+	if c.confirmEach && !c.yes {
+		if !isInteractive(in) {
+			return false, errNotInteractive
+		}
+
+		chosen, err := confirmEach(in, out, results.Folders)
+		if err != nil {
+			return false, err
+		}
+		if len(chosen) == 0 {
+			_, _ = fmt.Fprintf(out, "Nothing selected, nothing deleted\n")
+			return false, nil
+		}
+		results.Keep(chosen)
+	}
+
+	ok, err := confirmDelete(in, out, results, c)
+	if err == nil && !ok {
+		_, _ = fmt.Fprintf(out, "Aborted, nothing deleted\n")
+	}
+	return ok, err
+}
+
+// confirmDelete asks y/N before deleting, saying how many folders and how much
+// space, or with -confirm-over asks as confirmLargeDelete does. There is no
+// y/N prompt with -yes, once folders have been chosen with -interactive or
+// -confirm-each, or when stdin isn't a terminal, so scripts aren't held up.
+func confirmDelete(in io.Reader, out io.Writer, results *cleaner.Result, c *Config) (bool, error) {
+	if c.confirmOver > 0 && results.TotalSize > c.confirmOver {
+		return confirmLargeDelete(in, out, results, c)
+	}
+	if c.yes || c.interactive || c.confirmEach || !isInteractive(in) {
+		return true, nil
+	}
+
+	_, _ = fmt.Fprintf(out, "Delete %d folders, %s in total? [y/N] ", len(results.Folders), cleaner.FormatSize(results.TotalSize))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// confirmEach asks about each folder in turn for -confirm-each: y deletes it,
+// s skips it, a deletes it and all the rest, and q skips it and all the rest.
+// It returns the folders to delete.
+func confirmEach(in io.Reader, out io.Writer, folders []*cleaner.Folder) ([]*cleaner.Folder, error) {
+	r := bufio.NewReader(in)
+	var chosen []*cleaner.Folder
+	for i, f := range folders {
+		_, _ = fmt.Fprintf(out, "Delete %s (%s, %s days)? [y]es, [s]kip, [a]ll, [q]uit: ",
+			f.Path, cleaner.FormatSize(f.SizeBytes), groupThousands(f.ModDaysAgo))
+
+		answer, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			chosen = append(chosen, f)
+		case "a", "all":
+			return append(chosen, folders[i:]...), nil
+		case "q", "quit":
+			return chosen, nil
+		case "s", "skip":
+		default:
+			if err == io.EOF {
+				return chosen, nil
+			}
+			_, _ = fmt.Fprintf(out, "Skipped\n")
+		}
+	}
+	return chosen, nil
+}
+
+// confirmLargeDelete asks for a stronger confirmation when the total size to
+// be deleted is over the -confirm-over threshold. Rather than y/n, the user
+// must type the exact number of folders or the word DELETE.
+func confirmLargeDelete(in io.Reader, out io.Writer, results *cleaner.Result, c *Config) (bool, error) {
+	if c.confirmOver <= 0 || results.TotalSize <= c.confirmOver || c.yes {
+		return true, nil
+	}
+
+	if !isInteractive(in) {
+		return false, errNotInteractive
+	}
+
+	count := strconv.Itoa(len(results.Folders))
+	_, _ = fmt.Fprintf(out, "About to delete %s across %s folders, which is over the %s threshold.\n",
+		cleaner.FormatSize(results.TotalSize), count, cleaner.FormatSize(c.confirmOver))
+	_, _ = fmt.Fprintf(out, "Type %s or DELETE to continue: ", count)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	answer = strings.TrimSpace(answer)
+	return answer == count || answer == "DELETE", nil
+}
+
+// isInteractive reports whether r is a terminal that can answer a prompt.
+func isInteractive(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	// The null device is a character device too, and is what cron and
+	// service managers usually give as stdin.
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
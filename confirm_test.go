@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// notTerminal returns a stdin that is a file rather than a terminal, as
+// under cron.
+func notTerminal(t *testing.T) *os.File {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(p, []byte("y\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func TestConfirmDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(c *Config)
+		input   string
+		file    bool
+		want    bool
+		wantErr error
+		prompt  string
+	}{
+		{name: "yes", input: "y\n", want: true, prompt: "Delete 2 folders"},
+		{name: "no", input: "n\n", prompt: "Delete 2 folders"},
+		{name: "no answer", input: "", prompt: "Delete 2 folders"},
+		{name: "-yes", setup: func(c *Config) { c.yes = true }, want: true},
+		{name: "chosen with -confirm-each", setup: func(c *Config) { c.confirmEach = true }, want: true},
+		{name: "stdin not a terminal", file: true, want: true},
+		{
+			name:   "over -confirm-over",
+			setup:  func(c *Config) { c.confirmOver = 1 },
+			input:  "2\n",
+			want:   true,
+			prompt: "Type 2 or DELETE",
+		},
+		{
+			name:   "over -confirm-over, wrong count",
+			setup:  func(c *Config) { c.confirmOver = 1 },
+			input:  "y\n",
+			prompt: "Type 2 or DELETE",
+		},
+		{
+			name:    "over -confirm-over, stdin not a terminal",
+			setup:   func(c *Config) { c.confirmOver = 1 },
+			file:    true,
+			wantErr: errNotInteractive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, results := projects(t, "a", "b")
+			c := testConfig(root)
+			if tt.setup != nil {
+				tt.setup(c)
+			}
+			var in io.Reader = strings.NewReader(tt.input)
+			if tt.file {
+				in = notTerminal(t)
+			}
+
+			var out bytes.Buffer
+			got, err := confirmDelete(in, &out, results, c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("confirmed = %t, want %t", got, tt.want)
+			}
+			if tt.prompt == "" && out.Len() > 0 {
+				t.Errorf("prompted %q, want no prompt", out.String())
+			}
+			if !strings.Contains(out.String(), tt.prompt) {
+				t.Errorf("prompt = %q, want it to contain %q", out.String(), tt.prompt)
+			}
+		})
+	}
+}
+
+func TestConfirmEach(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{input: "y\ny\ny\n", want: []string{"a", "b", "c"}},
+		{input: "y\ns\ny\n", want: []string{"a", "c"}},
+		{input: "s\na\n", want: []string{"b", "c"}},
+		{input: "y\nq\n", want: []string{"a"}},
+		{input: "y\nwhat\ny\n", want: []string{"a", "c"}},
+		{input: "y\n", want: []string{"a"}},
+		{input: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.ReplaceAll(tt.input, "\n", ","), func(t *testing.T) {
+			_, results := projects(t, "a", "b", "c")
+			chosen, err := confirmEach(strings.NewReader(tt.input), io.Discard, results.Folders)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range chosen {
+				got = append(got, filepath.Base(f.Project))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chosen = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMachineOutputConfirms checks that -json and -porcelain ask before
+// deleting, and let folders be chosen, just as the table output does, on
+// stderr so stdout stays parseable.
+func TestMachineOutputConfirms(t *testing.T) {
+	outputs := map[string]func(context.Context, io.Reader, io.Writer, io.Writer, *Config, *cleaner.Result, error) int{
+		"json":      runJSON,
+		"porcelain": runPorcelain,
+	}
+	tests := []struct {
+		name     string
+		setup    func(c *Config)
+		input    string
+		file     bool
+		wantCode int
+		wantLeft []string
+		prompted bool
+	}{
+		{name: "-delete, yes", input: "y\n", prompted: true},
+		{name: "-delete, no", input: "n\n", wantLeft: []string{"a", "b"}, prompted: true},
+		{name: "-delete, stdin not a terminal", file: true},
+		{name: "-delete -yes", setup: func(c *Config) { c.yes = true }},
+		{
+			name:     "-confirm-each, one of two",
+			setup:    func(c *Config) { c.confirmEach = true },
+			input:    "s\ny\n",
+			wantLeft: []string{"a"},
+			prompted: true,
+		},
+		{
+			name:     "-confirm-each, quit",
+			setup:    func(c *Config) { c.confirmEach = true },
+			input:    "q\n",
+			wantLeft: []string{"a", "b"},
+			prompted: true,
+		},
+		{
+			name:  "-confirm-each -yes",
+			setup: func(c *Config) { c.confirmEach, c.yes = true, true },
+		},
+		{
+			name:     "-confirm-each, stdin not a terminal",
+			setup:    func(c *Config) { c.confirmEach = true },
+			file:     true,
+			wantCode: ExitError,
+			wantLeft: []string{"a", "b"},
+		},
+		{
+			name:     "-interactive, one of two",
+			setup:    func(c *Config) { c.interactive = true },
+			input:    "1\nd\n",
+			wantLeft: []string{"a"},
+			prompted: true,
+		},
+		{
+			name:     "-interactive, quit",
+			setup:    func(c *Config) { c.interactive = true },
+			input:    "q\n",
+			wantLeft: []string{"a", "b"},
+			prompted: true,
+		},
+		{
+			name:     "-interactive -yes",
+			setup:    func(c *Config) { c.interactive, c.yes = true, true },
+			input:    "2\nd\n",
+			wantLeft: []string{"b"},
+			prompted: true,
+		},
+		{
+			name:     "-interactive, stdin not a terminal",
+			setup:    func(c *Config) { c.interactive = true },
+			file:     true,
+			wantCode: ExitError,
+			wantLeft: []string{"a", "b"},
+		},
+	}
+	isPrompt := func(s string) bool {
+		return strings.Contains(s, "? [") || strings.Contains(s, interactiveHelp)
+	}
+
+	for format, run := range outputs {
+		for _, tt := range tests {
+			t.Run(format+" "+tt.name, func(t *testing.T) {
+				root, results := projects(t, "a", "b")
+				c := testConfig(root)
+				if tt.setup != nil {
+					tt.setup(c)
+				}
+				var in io.Reader = strings.NewReader(tt.input)
+				if tt.file {
+					in = notTerminal(t)
+				}
+
+				var stdout, stderr bytes.Buffer
+				code := run(context.Background(), in, &stdout, &stderr, c, results, nil)
+				if code != tt.wantCode {
+					t.Errorf("exit code = %d, want %d, stderr %q", code, tt.wantCode, stderr.String())
+				}
+				if left := remaining(t, root, "a", "b"); !reflect.DeepEqual(left, tt.wantLeft) {
+					t.Errorf("left = %q, want %q", left, tt.wantLeft)
+				}
+				if prompted := isPrompt(stderr.String()); prompted != tt.prompted {
+					t.Errorf("prompted = %t, want %t, stderr %q", prompted, tt.prompted, stderr.String())
+				}
+				if isPrompt(stdout.String()) {
+					t.Errorf("prompt written to stdout: %q", stdout.String())
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// Exit codes. A fatal error is always ExitError; the others are only used for
+// conditions listed in -fail-on, otherwise the run exits with ExitOK.
+const (
+	ExitOK           = 0
+	ExitError        = 1
+	ExitFound        = 2
+	ExitDeleteFailed = 3
+	ExitScanErrors   = 4
+)
+
+// Conditions that -fail-on can turn into a nonzero exit code.
+const (
+	FailOnFound       = "found"
+	FailOnDeleteError = "delete-error"
+	FailOnScanError   = "scan-error"
+	FailOnNone        = "none"
+)
+
+var failOnCodes = map[string]int{
+	FailOnFound:       ExitFound,
+	FailOnDeleteError: ExitDeleteFailed,
+	FailOnScanError:   ExitScanErrors,
+}
+
+// parseFailOn parses a comma separated list of -fail-on conditions.
+func parseFailOn(s string) (map[string]bool, error) {
+	conditions := make(map[string]bool)
+	for _, cond := range strings.Split(s, ",") {
+		cond = strings.TrimSpace(cond)
+		switch {
+		case cond == FailOnNone || cond == "":
+		case failOnCodes[cond] != 0:
+			conditions[cond] = true
+		default:
+			return nil, fmt.Errorf("unknown -fail-on %q", cond)
+		}
+	}
+	return conditions, nil
+}
+
+// exitCode returns the exit code for cond if it is in -fail-on, otherwise
+// ExitOK.
+func (c *Config) exitCode(cond string) int {
+	if c.failOnSet[cond] {
+		return failOnCodes[cond]
+	}
+	return ExitOK
+}
+
+// scanExitCode returns the exit code for a finished scan, before anything is
+// deleted. Folders that couldn't be read take precedence over folders found.
+func (c *Config) scanExitCode(results *cleaner.Result) int {
+	if len(results.Errors) > 0 && c.failOnSet[FailOnScanError] {
+		return ExitScanErrors
+	}
+	if !c.delete && len(results.Folders) > 0 {
+		return c.exitCode(FailOnFound)
+	}
+	return ExitOK
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+func TestScanExitCode(t *testing.T) {
+	found := []*cleaner.Folder{{Path: "/app/node_modules"}}
+	scanErrors := []*cleaner.ScanError{{Path: "/locked"}}
+
+	tests := []struct {
+		name    string
+		failOn  string
+		delete  bool
+		folders []*cleaner.Folder
+		errors  []*cleaner.ScanError
+		want    int
+	}{
+		{name: "nothing found", want: ExitOK},
+		{name: "found", folders: found, want: ExitFound},
+		{name: "found and deleting", delete: true, folders: found, want: ExitOK},
+		{name: "found, not failing on it", failOn: "delete-error,scan-error", folders: found, want: ExitOK},
+		{name: "scan errors", folders: found, errors: scanErrors, want: ExitScanErrors},
+		{name: "scan errors, not failing on them", failOn: "found", errors: scanErrors, want: ExitOK},
+		{name: "none", failOn: "none", folders: found, errors: scanErrors, want: ExitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig()
+			if tt.failOn != "" {
+				c.failOn = tt.failOn
+			}
+			var err error
+			if c.failOnSet, err = parseFailOn(c.failOn); err != nil {
+				t.Fatal(err)
+			}
+			c.delete = tt.delete
+
+			results := &cleaner.Result{Errors: tt.errors}
+			results.Keep(tt.folders)
+			if got := c.scanExitCode(results); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
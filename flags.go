@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// stringList is a flag that can be given more than once, collecting each
+// value.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// ageFlag is a flag holding a duration, parsed with ParseAge.
+type ageFlag time.Duration
+
+func (a *ageFlag) String() string {
+	if a == nil {
+		return "0"
+	}
+	return cleaner.FormatAge(time.Duration(*a))
+}
+
+func (a *ageFlag) Set(v string) error {
+	d, err := cleaner.ParseAge(v)
+	if err != nil {
+		return err
+	}
+	*a = ageFlag(d)
+	return nil
+}
+
+// sizeFlag is a flag holding a size in bytes, parsed with ParseSize.
+type sizeFlag int64
+
+func (s *sizeFlag) String() string {
+	if s == nil || *s == 0 {
+		return "0"
+	}
+	return cleaner.FormatSize(int64(*s))
+}
+
+func (s *sizeFlag) Set(v string) error {
+	b, err := cleaner.ParseSize(v)
+	if err != nil {
+		return err
+	}
+	*s = sizeFlag(b)
+	return nil
+}
+
+// scoreWeightsFlag is a flag holding score weights, parsed with
+// ParseScoreWeights.
+type scoreWeightsFlag cleaner.ScoreWeights
+
+func (w *scoreWeightsFlag) String() string {
+	if w == nil {
+		return ""
+	}
+	return cleaner.ScoreWeights(*w).String()
+}
+
+func (w *scoreWeightsFlag) Set(v string) error {
+	weights, err := cleaner.ParseScoreWeights(v)
+	if err != nil {
+		return err
+	}
+	*w = scoreWeightsFlag(weights)
+	return nil
+}
+
+// dirList is a flag that can be given more than once, collecting each value
+// like stringList. Unlike stringList, the values from the command line replace
+// those from the config file or environment instead of adding to them, and
+// def is used if none are given at all.
+type dirList struct {
+	dirs    []string
+	def     string
+	replace bool
+}
+
+func (d *dirList) String() string {
+	if d == nil {
+		return ""
+	}
+	return strings.Join(d.values(), ",")
+}
+
+func (d *dirList) Set(v string) error {
+	if d.replace {
+		d.dirs, d.replace = nil, false
+	}
+	d.dirs = append(d.dirs, v)
+	return nil
+}
+
+// override makes the next value set replace those set so far, called as each
+// source of settings is applied.
+func (d *dirList) override() {
+	d.replace = len(d.dirs) > 0
+}
+
+func (d *dirList) values() []string {
+	if len(d.dirs) == 0 {
+		return []string{d.def}
+	}
+	return d.dirs
+}
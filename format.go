@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatMarkdown = "md"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+)
+
+func validFormat(format string) bool {
+	switch format {
+	case FormatTable, FormatJSON, FormatMarkdown, FormatCSV, FormatTSV:
+		return true
+	}
+	return false
+}
+
+// writeMarkdown writes the results as Markdown tables for pasting into an
+// issue, pull request or wiki page, after a line saying what was scanned.
+func writeMarkdown(w io.Writer, c *Config, results *cleaner.Result) {
+	from := make([]string, 0, len(c.fromDirs.values()))
+	for _, dir := range c.fromDirs.values() {
+		from = append(from, markdownCode(dir))
+	}
+	_, _ = fmt.Fprintf(w, "Scanned %s on %s for folders not modified for %s and at least %s.\n",
+		strings.Join(from, ", "), time.Now().Format("2006-01-02"), cleaner.FormatAge(c.OlderThan), cleaner.FormatSize(c.MinSize))
+	if results.Partial {
+		_, _ = fmt.Fprintf(w, "\n**Partial results**: the scan stopped after %s, before it had looked everywhere.\n", c.MaxDuration)
+	}
+
+	if len(results.Folders) == 0 && len(results.Review) == 0 && len(results.Dirty) == 0 {
+		_, _ = fmt.Fprintf(w, "\nNo results found.\n")
+		return
+	}
+
+	if len(results.Folders) > 0 {
+		_, _ = fmt.Fprintf(w, "\n**%d folders, %s** can be deleted:\n\n", len(results.Folders), cleaner.FormatSize(results.TotalSize))
+		writeMarkdownTable(w, results.Folders)
+	}
+	if len(results.Review) > 0 {
+		_, _ = fmt.Fprintf(w, "\nNeeds review, larger than %s and never deleted automatically:\n\n", cleaner.FormatSize(c.MaxSize))
+		writeMarkdownTable(w, results.Review)
+	}
+	if len(results.Dirty) > 0 {
+		_, _ = fmt.Fprintf(w, "\nSkipped, uncommitted or unpushed changes in the project:\n\n")
+		writeMarkdownTable(w, results.Dirty)
+	}
+}
+
+func writeMarkdownTable(w io.Writer, folders []*cleaner.Folder) {
+	var total int64
+	_, _ = fmt.Fprintf(w, "| Path | Modified Days Ago | Size |\n")
+	_, _ = fmt.Fprintf(w, "|------|------------------:|-----:|\n")
+	for _, f := range folders {
+		_, _ = fmt.Fprintf(w, "| %s | %s | %s |\n", markdownCode(f.Path), groupThousands(f.ModDaysAgo), cleaner.FormatSize(f.SizeBytes))
+		total += f.SizeBytes
+	}
+	_, _ = fmt.Fprintf(w, "| **Total** | | **%s** |\n", cleaner.FormatSize(total))
+}
+
+// writeDelimited writes a header and then a row per folder found, separated
+// by sep, for loading into a spreadsheet. would_delete is false for folders
+// that are for review or have uncommitted changes.
+func writeDelimited(w io.Writer, sep rune, results *cleaner.Result) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	_ = cw.Write([]string{"path", "size_bytes", "size_mb", "mod_days_ago", "would_delete"})
+	rows := func(folders []*cleaner.Folder, wouldDelete bool) {
+		for _, f := range folders {
+			_ = cw.Write([]string{
+				f.Path,
+				strconv.FormatInt(f.SizeBytes, 10),
+				strconv.Itoa(bytesToMb(f.SizeBytes)),
+				strconv.Itoa(f.ModDaysAgo),
+				strconv.FormatBool(wouldDelete),
+			})
+		}
+	}
+	rows(results.Folders, true)
+	rows(results.Review, false)
+	rows(results.Dirty, false)
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownCode formats s as inline code that is safe inside a table cell.
+func markdownCode(s string) string {
+	return "`" + strings.ReplaceAll(s, "|", `\|`) + "`"
+}
@@ -1,3 +1,7 @@
 module npm-cleaner
 
 go 1.18
+
+require github.com/fsnotify/fsnotify v1.9.0
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// groupFolders rolls folders up by the folder depth levels below the start
+// folder holding their project, for -group-depth. Each group is returned as a
+// folder of its own, with the total size of its folders and the newest
+// modified time and highest score, so it can be sorted and selected like one, along with the
+// folders in each. A project less than depth levels down is a group of its
+// own.
+func groupFolders(folders []*cleaner.Folder, dirs []string, depth int, by string, reverse bool) ([]*cleaner.Folder, map[*cleaner.Folder][]*cleaner.Folder) {
+	var groups []*cleaner.Folder
+	byDir := make(map[string]*cleaner.Folder)
+	members := make(map[*cleaner.Folder][]*cleaner.Folder)
+	for _, f := range folders {
+		dir := groupDir(f, dirs, depth)
+		g := byDir[dir]
+		if g == nil {
+			g = &cleaner.Folder{Path: dir, ModTime: f.ModTime, ModDaysAgo: f.ModDaysAgo}
+			byDir[dir] = g
+			groups = append(groups, g)
+		}
+
+		g.SizeBytes += f.SizeBytes
+		g.ReclaimableBytes += f.ReclaimableBytes
+		g.PackageCount += f.PackageCount
+		if f.Score > g.Score {
+			g.Score = f.Score
+		}
+		if f.ModTime.After(g.ModTime) {
+			g.ModTime, g.ModDaysAgo = f.ModTime, f.ModDaysAgo
+		}
+		members[g] = append(members[g], f)
+	}
+
+	cleaner.SortFolders(groups, by, reverse)
+	return groups, members
+}
+
+// groupDir returns the folder f is grouped under: the one depth levels below
+// the deepest of dirs holding its project, or the project itself.
+func groupDir(f *cleaner.Folder, dirs []string, depth int) string {
+	project := f.Project
+	if project == "" {
+		project = filepath.Dir(f.Path)
+	}
+
+	start := startDirOf(dirs, project)
+	if start == "" {
+		return project
+	}
+
+	rel, err := filepath.Rel(start, project)
+	if err != nil || rel == "." {
+		return project
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if len(parts) <= depth {
+		return project
+	}
+	return filepath.Join(start, filepath.Join(parts[:depth]...))
+}
+
+// printGroups prints groups as a table in style s, with how many projects
+// each holds.
+func printGroups(s *tableStyle, groups []*cleaner.Folder, members map[*cleaner.Folder][]*cleaner.Folder) {
+	longestPath := len("Path")
+	for _, g := range groups {
+		if n := len([]rune(s.shorten(g.Path))); n > longestPath {
+			longestPath = n
+		}
+	}
+	longestPath++
+
+	row := func(path, projects, days, size string) {
+		fmt.Printf("%-"+strconv.Itoa(longestPath)+"s|%10s|%20s|%12s\n", path, projects, days, size)
+	}
+
+	var total int64
+	var count int
+	row("Path", "Projects", "Modified Days Ago", "Size")
+	for _, g := range groups {
+		projects := make(map[string]bool)
+		for _, f := range members[g] {
+			projects[f.Project] = true
+		}
+		row(s.shorten(g.Path), groupThousands(len(projects)), groupThousands(g.ModDaysAgo), cleaner.FormatSize(g.SizeBytes))
+		total += g.SizeBytes
+		count += len(projects)
+	}
+	row("Total", groupThousands(count), "", cleaner.FormatSize(total))
+}
+
+// ungroup returns the folders in each of groups.
+func ungroup(groups []*cleaner.Folder, members map[*cleaner.Folder][]*cleaner.Folder) []*cleaner.Folder {
+	var folders []*cleaner.Folder
+	for _, g := range groups {
+		folders = append(folders, members[g]...)
+	}
+	return folders
+}
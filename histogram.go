@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+func printHistogram(buckets []*cleaner.Bucket) {
+	fmt.Printf("%-12s|%10s|%12s|%12s\n", "Size", "Folders", "Total", "Cumulative")
+	var cumulative int64
+	for _, b := range buckets {
+		cumulative += b.TotalSize
+		fmt.Printf("%-12s|%10s|%12s|%12s\n", b.Label, groupThousands(b.Count),
+			cleaner.FormatSize(b.TotalSize), cleaner.FormatSize(cumulative))
+	}
+}
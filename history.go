@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+const HistoryFileEnv = "NPMCLEANER_HISTORY_FILE"
+
+// historyRun is one run that deleted something, stored as a line of JSON in
+// the history file.
+type historyRun struct {
+	Time       time.Time       `json:"time"`
+	Args       []string        `json:"args"`
+	Folders    []historyFolder `json:"folders"`
+	BytesFreed int64           `json:"bytesFreed"`
+}
+
+type historyFolder struct {
+	Path       string `json:"path"`
+	SizeBytes  int64  `json:"sizeBytes"`
+	BytesFreed int64  `json:"bytesFreed"`
+	Trashed    bool   `json:"trashed,omitempty"`
+}
+
+// historyFilePath returns the history file, NPMCLEANER_HISTORY_FILE if set or
+// npm-cleaner/history.jsonl in the user's config folder, next to the config
+// file.
+func historyFilePath() (string, error) {
+	if p := os.Getenv(HistoryFileEnv); p != "" {
+		return p, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "npm-cleaner", "history.jsonl"), nil
+}
+
+// recordHistory appends the folders that were deleted or trashed to the
+// history file. Failing to write it is only a warning, as the deletion itself
+// has already happened.
+func (c *Config) recordHistory(results *cleaner.Result, deleted []cleaner.DeleteResult) {
+	if !c.history {
+		return
+	}
+
+	sizes := make(map[string]int64, len(results.Folders))
+	for _, f := range results.Folders {
+		sizes[f.Path] = f.SizeBytes
+	}
+
+	run := historyRun{Time: time.Now(), Args: redactArgs(os.Args[1:])}
+	for _, r := range deleted {
+		if r.Err != nil {
+			continue
+		}
+		run.Folders = append(run.Folders, historyFolder{Path: r.Path, SizeBytes: sizes[r.Path], BytesFreed: r.BytesFreed, Trashed: r.Trashed})
+		run.BytesFreed += r.BytesFreed
+	}
+	if len(run.Folders) == 0 {
+		return
+	}
+
+	p, err := historyFilePath()
+	if err == nil {
+		err = appendJSONLine(p, run)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: recording history: %s\n", err)
+	}
+}
+
+// redactArgs returns args with the value of any -token flag hidden, so it
+// isn't saved in the history file.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i, arg := range redacted {
+		name := strings.TrimLeft(arg, "-")
+		switch {
+		case arg != name && name == "token" && i+1 < len(redacted):
+			redacted[i+1] = "REDACTED"
+		case arg != name && strings.HasPrefix(name, "token="):
+			redacted[i] = arg[:len(arg)-len(name)] + "token=REDACTED"
+		}
+	}
+	return redacted
+}
+
+// appendJSONLine appends v to the file at p as a line of JSON, creating the
+// file and its folder if needed.
+func appendJSONLine(p string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readJSONLines calls line with each line of the file at p. A missing file
+// has no lines.
+func readJSONLines(p string, line func([]byte)) error {
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// readHistory returns the runs in the history file from since onwards, oldest
+// first. A line that can't be parsed, such as one cut short by a crash, is
+// skipped.
+func readHistory(since time.Time) ([]historyRun, error) {
+	p, err := historyFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []historyRun
+	err = readJSONLines(p, func(line []byte) {
+		var run historyRun
+		if json.Unmarshal(line, &run) == nil && !run.Time.Before(since) {
+			runs = append(runs, run)
+		}
+	})
+	return runs, err
+}
+
+// runHistory prints the runs recorded since -since, or all of them, and the
+// space reclaimed in total.
+func runHistory(out io.Writer, c *Config) error {
+	var since time.Time
+	if c.historySince > 0 {
+		since = time.Now().Add(-c.historySince)
+	}
+
+	runs, err := readHistory(since)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		if runs == nil {
+			runs = []historyRun{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(runs)
+	}
+
+	if len(runs) == 0 {
+		_, _ = fmt.Fprintf(out, "No deletions recorded\n")
+		return nil
+	}
+
+	var total int64
+	folders := 0
+	_, _ = fmt.Fprintf(out, "%-17s|%8s|%12s| %s\n", "Date", "Folders", "Freed", "Command")
+	for _, run := range runs {
+		_, _ = fmt.Fprintf(out, "%-17s|%8d|%12s| %s\n", run.Time.Local().Format("2006-01-02 15:04"),
+			len(run.Folders), cleaner.FormatSize(run.BytesFreed), strings.Join(run.Args, " "))
+		total += run.BytesFreed
+		folders += len(run.Folders)
+	}
+	_, _ = fmt.Fprintf(out, "\n%s reclaimed from %d folders in %d runs since %s\n",
+		cleaner.FormatSize(total), folders, len(runs), runs[0].Time.Local().Format("2006-01-02"))
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// htmlReport is what the -report page shows.
+type htmlReport struct {
+	Generated string
+	From      []string
+	OlderThan string
+	MinSize   string
+	Rows      []htmlRow
+	Dirs      []htmlDir
+	Total     string
+	Count     int
+	Errors    []string
+}
+
+type htmlRow struct {
+	Path       string
+	Status     string
+	SizeBytes  int64
+	Size       string
+	ModDaysAgo int
+}
+
+// htmlDir is the total size of the folders found below one folder directly
+// inside a start folder, drawn as a bar.
+type htmlDir struct {
+	Path    string
+	Size    string
+	Count   int
+	Percent float64
+}
+
+// writeHTMLReport writes a standalone page to p listing every folder found,
+// with a bar chart of where the space is and a table sortable by clicking its
+// headings. It needs nothing but a browser, so can be shared as it is.
+func writeHTMLReport(p string, c *Config, results *cleaner.Result) error {
+	report := htmlReport{
+		Generated: time.Now().Format("2006-01-02 15:04"),
+		From:      c.fromDirs.values(),
+		OlderThan: cleaner.FormatAge(c.OlderThan),
+		MinSize:   cleaner.FormatSize(c.MinSize),
+		Total:     cleaner.FormatSize(results.TotalSize),
+		Count:     len(results.Folders),
+	}
+
+	sizes := make(map[string]int64)
+	counts := make(map[string]int)
+	var largest int64
+	add := func(folders []*cleaner.Folder, status string) {
+		for _, f := range folders {
+			report.Rows = append(report.Rows, htmlRow{
+				Path:       f.Path,
+				Status:     status,
+				SizeBytes:  f.SizeBytes,
+				Size:       cleaner.FormatSize(f.SizeBytes),
+				ModDaysAgo: f.ModDaysAgo,
+			})
+
+			dir := topDir(report.From, f.Path)
+			sizes[dir] += f.SizeBytes
+			counts[dir]++
+			if sizes[dir] > largest {
+				largest = sizes[dir]
+			}
+		}
+	}
+	add(results.Folders, "delete")
+	add(results.Review, "review")
+	add(results.Dirty, "dirty")
+
+	for dir, size := range sizes {
+		d := htmlDir{Path: dir, Size: cleaner.FormatSize(size), Count: counts[dir]}
+		if largest > 0 {
+			d.Percent = float64(size) * 100 / float64(largest)
+		}
+		report.Dirs = append(report.Dirs, d)
+	}
+	sort.Slice(report.Dirs, func(i, j int) bool {
+		if sizes[report.Dirs[i].Path] != sizes[report.Dirs[j].Path] {
+			return sizes[report.Dirs[i].Path] > sizes[report.Dirs[j].Path]
+		}
+		return report.Dirs[i].Path < report.Dirs[j].Path
+	})
+
+	for _, err := range results.Errors {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if err := htmlReportTemplate.Execute(f, report); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// topDir returns the folder directly inside whichever of dirs path is below
+// that path is in, or the start folder itself for a folder found directly in
+// it.
+func topDir(dirs []string, path string) string {
+	for _, dir := range dirs {
+		if !isBelow(dir, path) {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+		first := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+		if first == rel {
+			return dir
+		}
+		return filepath.Join(dir, first)
+	}
+	return filepath.Dir(path)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>npm-cleaner report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 4px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+th { cursor: pointer; background: #f4f4f4; user-select: none; }
+td.num, th.num { text-align: right; }
+.bar { background: #c0392b; height: 1em; }
+.review { color: #b7791f; }
+.dirty { color: #666; }
+</style>
+</head>
+<body>
+<h1>npm-cleaner report</h1>
+<p>Generated {{.Generated}} from {{range $i, $d := .From}}{{if $i}}, {{end}}<code>{{$d}}</code>{{end}},
+folders not modified for {{.OlderThan}} and at least {{.MinSize}}.</p>
+<p><strong>{{.Count}} folders, {{.Total}}</strong> can be deleted.</p>
+
+<h2>By folder</h2>
+<table>
+<tr><th>Folder</th><th class="num">Found</th><th class="num">Size</th><th style="width: 40%"></th></tr>
+{{range .Dirs}}<tr><td>{{.Path}}</td><td class="num">{{.Count}}</td><td class="num">{{.Size}}</td><td><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{end}}</table>
+
+<h2>Folders</h2>
+<table id="folders">
+<thead><tr><th>Path</th><th>Status</th><th class="num">Modified Days Ago</th><th class="num">Size</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr class="{{.Status}}"><td>{{.Path}}</td><td>{{.Status}}</td><td class="num" data-sort="{{.ModDaysAgo}}">{{.ModDaysAgo}}</td><td class="num" data-sort="{{.SizeBytes}}">{{.Size}}</td></tr>
+{{end}}</tbody>
+</table>
+{{if .Errors}}
+<h2>Skipped because of errors</h2>
+<ul>
+{{range .Errors}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}
+<script>
+document.querySelectorAll("#folders th").forEach(function (th, col) {
+  var asc = false;
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#folders tbody");
+    var rows = Array.prototype.slice.call(body.rows);
+    asc = !asc;
+    rows.sort(function (a, b) {
+      var x = a.cells[col], y = b.cells[col];
+      var d = x.dataset.sort !== undefined
+        ? Number(x.dataset.sort) - Number(y.dataset.sort)
+        : x.textContent.localeCompare(y.textContent);
+      return asc ? d : -d;
+    });
+    rows.forEach(function (r) { body.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`))
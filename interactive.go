@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+const interactiveHelp = `Enter folder numbers to toggle (e.g. "1 3 5" or "2-4"),
+  a  select all      n  select none
+  s  sort by size    o  sort by age
+  d  delete selected q  quit without deleting
+`
+
+// selectFolders lets the user pick which folders to delete by toggling
+// checkboxes on a numbered list. All folders start selected. It returns the
+// selected folders, or nil if the user quit.
+func selectFolders(in io.Reader, out io.Writer, folders []*cleaner.Folder) ([]*cleaner.Folder, error) {
+	list := make([]*cleaner.Folder, len(folders))
+	copy(list, folders)
+
+	selected := make(map[*cleaner.Folder]bool, len(list))
+	for _, f := range list {
+		selected[f] = true
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		printSelection(out, list, selected)
+		_, _ = fmt.Fprintf(out, "%s> ", interactiveHelp)
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		}
+
+		switch cmd := strings.TrimSpace(scanner.Text()); cmd {
+		case "a":
+			for _, f := range list {
+				selected[f] = true
+			}
+		case "n":
+			for _, f := range list {
+				selected[f] = false
+			}
+		case "s":
+			cleaner.SortFolders(list, cleaner.SortSize, false)
+		case "o":
+			cleaner.SortFolders(list, cleaner.SortAge, false)
+		case "d":
+			chosen := make([]*cleaner.Folder, 0, len(list))
+			for _, f := range list {
+				if selected[f] {
+					chosen = append(chosen, f)
+				}
+			}
+			return chosen, nil
+		case "q":
+			return nil, nil
+		default:
+			indexes, err := parseSelection(cmd, len(list))
+			if err != nil {
+				_, _ = fmt.Fprintf(out, "%s\n", err)
+				continue
+			}
+			for _, i := range indexes {
+				selected[list[i]] = !selected[list[i]]
+			}
+		}
+	}
+}
+
+func printSelection(out io.Writer, list []*cleaner.Folder, selected map[*cleaner.Folder]bool) {
+	var totalSize int64
+	for i, f := range list {
+		box := "[ ]"
+		if selected[f] {
+			box = "[x]"
+			totalSize += f.SizeBytes
+		}
+		_, _ = fmt.Fprintf(out, "%3d %s %s (%s, %s days)\n", i+1, box, f.Path,
+			cleaner.FormatSize(f.SizeBytes), groupThousands(f.ModDaysAgo))
+	}
+	_, _ = fmt.Fprintf(out, "Selected: %s\n", cleaner.FormatSize(totalSize))
+}
+
+// parseSelection parses space separated 1-based numbers and ranges such as
+// "1 3 5-7" into 0-based indexes.
+func parseSelection(s string, n int) ([]int, error) {
+	var indexes []int
+	for _, field := range strings.Fields(s) {
+		from, to := field, field
+		if i := strings.Index(field, "-"); i > 0 {
+			from, to = field[:i], field[i+1:]
+		}
+
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("unknown command %q", field)
+		}
+		end, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, fmt.Errorf("unknown command %q", field)
+		}
+		if start < 1 || end > n || start > end {
+			return nil, fmt.Errorf("%q is out of range 1-%d", field, n)
+		}
+
+		for i := start; i <= end; i++ {
+			indexes = append(indexes, i-1)
+		}
+	}
+
+	return indexes, nil
+}
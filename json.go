@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+type jsonFolder struct {
+	Path             string `json:"path"`
+	SizeBytes        int64  `json:"sizeBytes"`
+	SizeMb           int    `json:"sizeMb"`
+	ReclaimableBytes int64  `json:"reclaimableBytes"`
+	ModDaysAgo       int    `json:"modDaysAgo"`
+	PackageCount     int    `json:"packageCount,omitempty"`
+	// Score is only set when sorting by score.
+	Score float64 `json:"score,omitempty"`
+	// Artifact is set for build output, such as dist.
+	Artifact bool `json:"artifact,omitempty"`
+	// Members are the folders of workspace packages deleted along with it.
+	Members []string `json:"members,omitempty"`
+	// PackageManager and InstallCommand are only set for projects with a
+	// lockfile.
+	PackageManager string   `json:"packageManager,omitempty"`
+	InstallCommand []string `json:"installCommand,omitempty"`
+	// Breakdown is only set with -breakdown.
+	Breakdown []jsonPackageSize `json:"breakdown,omitempty"`
+}
+
+type jsonPackageSize struct {
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
+type jsonDeleteResult struct {
+	Path       string `json:"path"`
+	Deleted    bool   `json:"deleted"`
+	BytesFreed int64  `json:"bytesFreed"`
+	Trashed    bool   `json:"trashed"`
+	Archive    string `json:"archive,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type jsonVolume struct {
+	Path            string `json:"path"`
+	FreedBytes      int64  `json:"freedBytes"`
+	EstimatedBytes  int64  `json:"estimatedBytes"`
+	Mismatched      bool   `json:"mismatched"`
+	FreeBytesBefore int64  `json:"freeBytesBefore"`
+	FreeBytesAfter  int64  `json:"freeBytesAfter"`
+}
+
+// jsonStats are the scan's ScanStats, and how much was reclaimed if anything
+// was deleted.
+type jsonStats struct {
+	FoldersVisited   int            `json:"foldersVisited"`
+	TargetsSeen      int            `json:"targetsSeen"`
+	Candidates       int            `json:"candidates"`
+	Skipped          map[string]int `json:"skipped"`
+	Errors           int            `json:"errors"`
+	DurationMs       int64          `json:"durationMs"`
+	ReclaimableBytes int64          `json:"reclaimableBytes"`
+	ReclaimedBytes   int64          `json:"reclaimedBytes"`
+}
+
+type jsonReport struct {
+	Folders               []jsonFolder       `json:"folders"`
+	Review                []jsonFolder       `json:"review"`
+	Dirty                 []jsonFolder       `json:"dirty"`
+	TotalSizeBytes        int64              `json:"totalSizeBytes"`
+	TotalSizeMb           int                `json:"totalSizeMb"`
+	TotalReclaimableBytes int64              `json:"totalReclaimableBytes"`
+	Deleted               []jsonDeleteResult `json:"deleted,omitempty"`
+	Volumes               []jsonVolume       `json:"volumes,omitempty"`
+	Errors                []string           `json:"errors"`
+	Stats                 jsonStats          `json:"stats"`
+	// Partial is set if the scan stopped at -max-duration.
+	Partial bool `json:"partial"`
+}
+
+func newJSONReport(results *cleaner.Result) *jsonReport {
+	report := &jsonReport{
+		Folders: make([]jsonFolder, 0),
+		Review:  make([]jsonFolder, 0),
+		Dirty:   make([]jsonFolder, 0),
+		Errors:  make([]string, 0),
+		Stats:   jsonStats{Skipped: make(map[string]int)},
+	}
+
+	if results == nil {
+		return report
+	}
+
+	report.Partial = results.Partial
+	s := results.Stats
+	report.Stats = jsonStats{
+		FoldersVisited:   s.FoldersVisited,
+		TargetsSeen:      s.TargetsSeen,
+		Candidates:       s.Candidates,
+		Skipped:          s.Skipped,
+		Errors:           s.Errors,
+		DurationMs:       s.Duration.Milliseconds(),
+		ReclaimableBytes: results.TotalReclaimable,
+	}
+	if report.Stats.Skipped == nil {
+		report.Stats.Skipped = make(map[string]int)
+	}
+
+	report.TotalSizeBytes = results.TotalSize
+	report.TotalSizeMb = bytesToMb(results.TotalSize)
+	report.TotalReclaimableBytes = results.TotalReclaimable
+	for _, f := range results.Folders {
+		report.Folders = append(report.Folders, toJSONFolder(f))
+	}
+	for _, f := range results.Review {
+		report.Review = append(report.Review, toJSONFolder(f))
+	}
+	for _, f := range results.Dirty {
+		report.Dirty = append(report.Dirty, toJSONFolder(f))
+	}
+	for _, err := range results.Errors {
+		report.addError(err)
+	}
+
+	return report
+}
+
+func toJSONFolder(f *cleaner.Folder) jsonFolder {
+	j := jsonFolder{
+		Path:             f.Path,
+		SizeBytes:        f.SizeBytes,
+		SizeMb:           bytesToMb(f.SizeBytes),
+		ReclaimableBytes: f.ReclaimableBytes,
+		ModDaysAgo:       f.ModDaysAgo,
+		PackageCount:     f.PackageCount,
+		Score:            math.Round(f.Score*10) / 10,
+		Artifact:         f.Artifact,
+		Members:          f.Members,
+		PackageManager:   f.PackageManager,
+	}
+	if f.PackageManager != "" {
+		j.InstallCommand = cleaner.InstallCommand(f.Project, f.PackageManager)
+	}
+	return j
+}
+
+// addBreakdowns sets the breakdown of each of the report's folders, which
+// are folders in the same order.
+func (j *jsonReport) addBreakdowns(folders []*cleaner.Folder, sizes map[*cleaner.Folder][]cleaner.PackageSize) {
+	for i, f := range folders {
+		for _, p := range sizes[f] {
+			j.Folders[i].Breakdown = append(j.Folders[i].Breakdown, jsonPackageSize{Name: p.Name, SizeBytes: p.SizeBytes})
+		}
+	}
+}
+
+func (j *jsonReport) addError(err error) {
+	j.Errors = append(j.Errors, err.Error())
+}
+
+func (j *jsonReport) addDeleted(deleted []cleaner.DeleteResult) {
+	for _, r := range deleted {
+		d := jsonDeleteResult{
+			Path:       r.Path,
+			Deleted:    r.Deleted,
+			BytesFreed: r.BytesFreed,
+			Trashed:    r.Trashed,
+			Archive:    r.Archive,
+		}
+		if r.Err != nil {
+			d.Error = r.Err.Error()
+			j.addError(r.Err)
+		}
+		j.Stats.ReclaimedBytes += r.BytesFreed
+		j.Deleted = append(j.Deleted, d)
+	}
+}
+
+func (j *jsonReport) addVolumes(volumes []*cleaner.Volume) {
+	for _, v := range volumes {
+		if v.Err != nil {
+			continue
+		}
+		j.Volumes = append(j.Volumes, jsonVolume{
+			Path:            v.Path,
+			FreedBytes:      v.Freed(),
+			EstimatedBytes:  v.Estimated,
+			Mismatched:      v.Mismatched(),
+			FreeBytesBefore: v.FreeBefore,
+			FreeBytesAfter:  v.FreeAfter,
+		})
+	}
+}
+
+func (j *jsonReport) write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j)
+}
+
+func bytesToMb(b int64) int {
+	return int(b / cleaner.MB)
+}
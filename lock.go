@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// lockInfo is written to a lock file by the run holding it, for the message
+// shown to any other run.
+type lockInfo struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Dir     string    `json:"dir"`
+}
+
+// heldLocks keeps the lock files open, as they would be closed, releasing
+// the locks, once nothing refers to them.
+var heldLocks []*os.File
+
+type lockedError struct {
+	info lockInfo
+}
+
+func (e *lockedError) Error() string {
+	return fmt.Sprintf("another instance is running on %s since %s (pid %d), run with -force-unlock if it isn't",
+		e.info.Dir, e.info.Started.Format("2006-01-02 15:04"), e.info.PID)
+}
+
+// lockDirs takes a lock for each of dirs in the user's cache folder, so two
+// runs, such as a scheduled one and one started by hand, don't scan and
+// delete the same folders at once. A folder is also refused while another run
+// holds a lock on one above or below it, as their scans would overlap. The
+// locks are held by the operating system and go with the process, so a run
+// that is killed doesn't leave one behind. With force a lock held by another
+// run is broken, and overlapping ones are ignored. The locks are released
+// when the process exits.
+func lockDirs(dirs []string, force bool) error {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	locks := filepath.Join(cache, "npm-cleaner", "locks")
+	if err := os.MkdirAll(locks, 0o755); err != nil {
+		return err
+	}
+
+	// Runs take their locks one at a time, so each sees the folders of the
+	// locks already held when it checks for overlaps.
+	guard, err := os.OpenFile(filepath.Join(locks, "guard"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer guard.Close()
+	if err := cleaner.Lock(guard); err != nil {
+		return err
+	}
+	defer func() { _ = cleaner.Unlock(guard) }()
+
+	held := make(map[string]bool)
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		sum := sha256.Sum256([]byte(dir))
+		p := filepath.Join(locks, hex.EncodeToString(sum[:8])+".lock")
+		if held[p] {
+			continue
+		}
+		if err := lockDir(p, dir, force); err != nil {
+			return err
+		}
+		held[p] = true
+		if force {
+			continue
+		}
+		if err := checkOverlap(locks, dir, held); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOverlap returns an error if another run holds a lock in locks on a
+// folder above or below dir. held are the lock files this run holds.
+func checkOverlap(locks, dir string, held map[string]bool) error {
+	entries, err := os.ReadDir(locks)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := filepath.Join(locks, e.Name())
+		if held[p] || filepath.Ext(p) != ".lock" {
+			continue
+		}
+		f, err := os.OpenFile(p, os.O_RDWR, 0)
+		if err != nil {
+			continue
+		}
+		locked, err := cleaner.TryLock(f)
+		if err != nil || locked {
+			_ = f.Close()
+			continue
+		}
+
+		var info lockInfo
+		data, _ := io.ReadAll(f)
+		_ = f.Close()
+		if json.Unmarshal(data, &info) == nil && (isBelow(info.Dir, dir) || isBelow(dir, info.Dir)) {
+			return &lockedError{info}
+		}
+	}
+	return nil
+}
+
+func lockDir(p, dir string, force bool) error {
+	f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	locked, err := cleaner.TryLock(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if !locked {
+		var info lockInfo
+		data, _ := io.ReadAll(f)
+		_ = f.Close()
+		if !force {
+			if json.Unmarshal(data, &info) != nil {
+				info = lockInfo{Dir: dir}
+			}
+			return &lockedError{info}
+		}
+
+		// The other run keeps its lock on the file it opened, so breaking
+		// it means replacing the file. Where that isn't allowed, as on
+		// Windows, carry on without a lock.
+		if err := os.Remove(p); err != nil {
+			return nil
+		}
+		return lockDir(p, dir, false)
+	}
+
+	data, err := json.Marshal(lockInfo{PID: os.Getpid(), Started: time.Now(), Dir: dir})
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	heldLocks = append(heldLocks, f)
+	return nil
+}
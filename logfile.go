@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+const (
+	LogFormatJSON   = "json"
+	LogFormatLogfmt = "logfmt"
+)
+
+func validLogFormat(format string) bool {
+	return format == LogFormatJSON || format == LogFormatLogfmt
+}
+
+// runLog appends a record of each folder a run finds, skips, sizes and
+// deletes, and why, to -log-file, so unattended runs leave a trail that can be
+// searched later. Each record is a line of JSON or logfmt with the time, the
+// user running and their process ID. A nil runLog records nothing.
+type runLog struct {
+	mu     sync.Mutex
+	f      *os.File
+	format string
+	user   string
+	pid    int
+}
+
+// openRunLog opens the log file at p for appending, creating it and its folder
+// if needed.
+func openRunLog(p, format string) (*runLog, error) {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strconv.Itoa(os.Getuid())
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return &runLog{f: f, format: format, user: name, pid: os.Getpid()}, nil
+}
+
+// record writes a record of event with fields, given as name and value
+// pairs. Failing to write it is ignored, as it mustn't stop the run.
+func (l *runLog) record(event string, fields ...interface{}) {
+	if l == nil {
+		return
+	}
+
+	all := append([]interface{}{
+		"time", time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		"event", event,
+		"user", l.user,
+		"pid", l.pid,
+	}, fields...)
+
+	var b strings.Builder
+	for i := 0; i+1 < len(all); i += 2 {
+		name, value := all[i].(string), all[i+1]
+		if l.format == LogFormatLogfmt {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(name + "=" + logfmtValue(value))
+			continue
+		}
+
+		if i == 0 {
+			b.WriteByte('{')
+		} else {
+			b.WriteByte(',')
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(value))
+		}
+		b.WriteString(strconv.Quote(name) + ":" + string(data))
+	}
+	if l.format != LogFormatLogfmt {
+		b.WriteByte('}')
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.f.WriteString(b.String())
+}
+
+// logfmtValue formats v for logfmt, quoting it if it is empty or has spaces,
+// quotes or an equals sign.
+func logfmtValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if list, ok := v.([]string); ok {
+		s = strings.Join(list, " ")
+	}
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=\\") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// event records e. Folders walked into aren't recorded, as there is one for
+// every folder scanned, but those not looked inside are, with why.
+func (l *runLog) event(c *Config, e cleaner.Event) {
+	switch e.Kind {
+	case cleaner.FolderFound:
+		l.record("found", "path", e.Folder.Path, "project", e.Folder.Project)
+	case cleaner.FolderSkipped:
+		l.record("skipped", "path", e.Folder.Path, "reason", e.Reason)
+	case cleaner.FolderVisited:
+		if e.Reason != "" {
+			l.record("skipped", "path", e.Folder.Path, "reason", e.Reason)
+		}
+	case cleaner.SizeComputed:
+		l.record("sized", "path", e.Folder.Path, "sizeBytes", e.Folder.SizeBytes)
+	case cleaner.Deleted:
+		fields := []interface{}{"path", e.Folder.Path, "sizeBytes", e.Folder.SizeBytes, "trash", c.Trash}
+		if c.ArchiveDir != "" {
+			fields = append(fields, "archive", c.ArchiveDir)
+		}
+		l.record("deleted", fields...)
+	case cleaner.Error:
+		l.record("error", "path", e.Folder.Path, "error", e.Err.Error())
+	}
+}
+
+// scanned records the folders a scan chose for deleting, or why it failed.
+func (l *runLog) scanned(results *cleaner.Result, err error) {
+	if err != nil {
+		l.record("scan-error", "error", err.Error())
+	}
+	if results == nil {
+		return
+	}
+	if results.Partial {
+		l.record("partial", "reason", "stopped at -max-duration")
+	}
+	for _, f := range results.Folders {
+		l.record("candidate", "path", f.Path, "sizeBytes", f.SizeBytes, "modDaysAgo", f.ModDaysAgo)
+	}
+}
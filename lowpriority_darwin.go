@@ -0,0 +1,14 @@
+package main
+
+import "syscall"
+
+const (
+	prioDarwinProcess = 4
+	prioDarwinBG      = 0x1000
+)
+
+// lowerPriority puts the process in the background state, which throttles
+// its disk I/O and lowers its CPU priority, as taskpolicy -b does.
+func lowerPriority() error {
+	return syscall.Setpriority(prioDarwinProcess, 0, prioDarwinBG)
+}
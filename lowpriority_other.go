@@ -0,0 +1,12 @@
+//go:build !windows && !linux && !darwin
+
+package main
+
+import "syscall"
+
+// lowerPriority sets the lowest CPU priority, as nice -n 19 does. There is no
+// portable way to lower I/O priority on the BSDs, though the disk is shared
+// more evenly with fewer workers.
+func lowerPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, 19)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "syscall"
+
+const processModeBackgroundBegin = 0x00100000
+
+var procSetPriorityClass = syscall.NewLazyDLL("kernel32.dll").NewProc("SetPriorityClass")
+
+// lowerPriority puts the process in background mode, which gives it very low
+// I/O and memory priority as well as CPU priority.
+func lowerPriority() error {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+
+	ok, _, callErr := procSetPriorityClass.Call(uintptr(process), processModeBackgroundBegin)
+	if ok == 0 {
+		return callErr
+	}
+	return nil
+}
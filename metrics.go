@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// runMetrics are the gauges written to -metrics-file after each run, for
+// monitoring disk use across many machines.
+type runMetrics struct {
+	Found          int
+	FoundBytes     int64
+	Candidates     int
+	CandidateBytes int64
+	ScanErrors     int
+	ScanDuration   time.Duration
+	ReclaimedBytes int64
+	Time           time.Time
+}
+
+func newRunMetrics(results *cleaner.Result, scanDuration time.Duration, deleted []cleaner.DeleteResult) runMetrics {
+	m := runMetrics{
+		Candidates:     len(results.Folders),
+		CandidateBytes: results.TotalSize,
+		ScanErrors:     len(results.Errors),
+		ScanDuration:   scanDuration,
+		Time:           time.Now(),
+	}
+	for _, folders := range [][]*cleaner.Folder{results.Folders, results.Review, results.Dirty} {
+		for _, f := range folders {
+			m.Found++
+			m.FoundBytes += f.SizeBytes
+		}
+	}
+	for _, r := range deleted {
+		m.ReclaimedBytes += r.BytesFreed
+	}
+	return m
+}
+
+// write writes m in the Prometheus text format.
+func (m runMetrics) write(w io.Writer) {
+	gauge := func(name, help string, value interface{}) {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, value)
+	}
+	gauge("npm_cleaner_found_folders", "Target folders found, including those for review or with uncommitted changes.", m.Found)
+	gauge("npm_cleaner_found_bytes", "Total size of the target folders found.", m.FoundBytes)
+	gauge("npm_cleaner_candidate_folders", "Folders that can be deleted.", m.Candidates)
+	gauge("npm_cleaner_candidate_bytes", "Total size of the folders that can be deleted.", m.CandidateBytes)
+	gauge("npm_cleaner_scan_errors", "Folders skipped because of errors.", m.ScanErrors)
+	gauge("npm_cleaner_scan_duration_seconds", "How long the last scan took.", m.ScanDuration.Seconds())
+	gauge("npm_cleaner_reclaimed_bytes", "Space freed by the last run.", m.ReclaimedBytes)
+	gauge("npm_cleaner_last_run_timestamp_seconds", "When the last run finished, in seconds since the Unix epoch.", m.Time.Unix())
+}
+
+// writeMetrics writes the metrics for this run to -metrics-file, if set, for
+// node_exporter's textfile collector. The file is replaced in one step so the
+// collector never reads it half written. Failing to write it is only a
+// warning.
+func (c *Config) writeMetrics(results *cleaner.Result, deleted []cleaner.DeleteResult) {
+	if c.metricsFile == "" {
+		return
+	}
+
+	if err := writeMetricsFile(c.metricsFile, newRunMetrics(results, c.scanDuration, deleted)); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: writing metrics: %s\n", err)
+	}
+}
+
+func writeMetricsFile(p string, m runMetrics) error {
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	m.write(f)
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
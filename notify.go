@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// runSummary describes the outcome of a run in a sentence, for
+// notifications.
+func runSummary(results *cleaner.Result, deleted []cleaner.DeleteResult) string {
+	partial := ""
+	if results.Partial {
+		partial = ", from a partial scan"
+	}
+	if deleted == nil {
+		if len(results.Folders) == 0 {
+			return "No folders found to delete" + partial
+		}
+		return fmt.Sprintf("Found %d folders to delete, %s in total%s", len(results.Folders), cleaner.FormatSize(results.TotalSize), partial)
+	}
+
+	count, failed := 0, 0
+	var freed int64
+	for _, r := range deleted {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		count++
+		freed += r.BytesFreed
+	}
+
+	summary := fmt.Sprintf("Deleted %d folders, freeing %s%s", count, cleaner.FormatSize(freed), partial)
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d couldn't be deleted", failed)
+	}
+	return summary
+}
+
+// notifyDone shows a desktop notification summarising the run when -notify
+// is set, writes each user's summary with -notify-users, and posts the summary
+// to -notify-url if set. Failing to do any of them is only a warning.
+func (c *Config) notifyDone(results *cleaner.Result, deleted []cleaner.DeleteResult) {
+	c.runLog.record("done", "summary", runSummary(results, deleted))
+
+	if c.notify {
+		if err := desktopNotify("npm-cleaner", runSummary(results, deleted)); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: desktop notification: %s\n", err)
+		}
+	}
+
+	if c.notifyUsers && c.PerUser {
+		c.writeUserSummaries(results, deleted)
+	}
+
+	if c.notifyURL != "" {
+		if err := postWebhook(c.notifyURL, newWebhookPayload(results, deleted)); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: posting to -notify-url: %s\n", err)
+		}
+	}
+}
+
+// webhookPayload is posted to -notify-url. Slack and Teams incoming webhooks
+// show text and ignore the other fields, which are there for anything else
+// receiving it.
+type webhookPayload struct {
+	Text           string   `json:"text"`
+	Host           string   `json:"host"`
+	FoldersFound   int      `json:"foldersFound"`
+	FoldersDeleted int      `json:"foldersDeleted"`
+	BytesFreed     int64    `json:"bytesFreed"`
+	MbFreed        int      `json:"mbFreed"`
+	Errors         []string `json:"errors"`
+}
+
+func newWebhookPayload(results *cleaner.Result, deleted []cleaner.DeleteResult) webhookPayload {
+	host, _ := os.Hostname()
+	p := webhookPayload{
+		Text:         fmt.Sprintf("npm-cleaner on %s: %s", host, runSummary(results, deleted)),
+		Host:         host,
+		FoldersFound: len(results.Folders),
+		Errors:       make([]string, 0),
+	}
+	for _, err := range results.Errors {
+		p.Errors = append(p.Errors, err.Error())
+	}
+	for _, r := range deleted {
+		if r.Err != nil {
+			p.Errors = append(p.Errors, fmt.Sprintf("%s: %s", r.Path, r.Err))
+			continue
+		}
+		p.FoldersDeleted++
+		p.BytesFreed += r.BytesFreed
+	}
+	p.MbFreed = bytesToMb(p.BytesFreed)
+	if len(p.Errors) > 0 {
+		p.Text += fmt.Sprintf(" (%d errors)", len(p.Errors))
+	}
+	return p
+}
+
+// webhookTimeout is how long to wait for -notify-url to answer.
+const webhookTimeout = 10 * time.Second
+
+func postWebhook(url string, payload webhookPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// notifyScript shows a Notification Center notification, taking the title
+// and message as arguments so they need no quoting.
+const notifyScript = `on run argv
+	display notification (item 2 of argv) with title (item 1 of argv)
+end run`
+
+func desktopNotify(title, message string) error {
+	out, err := exec.Command("osascript", "-e", notifyScript, title, message).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("osascript: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
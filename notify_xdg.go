@@ -0,0 +1,19 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// desktopNotify shows a notification with notify-send, which comes with most
+// Linux and BSD desktops.
+func desktopNotify(title, message string) error {
+	out, err := exec.Command("notify-send", "--app-name=npm-cleaner", title, message).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify-send: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
@@ -1,257 +1,915 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"regexp"
-	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"npm-cleaner/pkg/cleaner"
 )
 
-const NodeModules = "node_modules"
+// invertedBool is a boolean flag that sets the opposite of its value.
+type invertedBool bool
 
-var excludeFolders = []*regexp.Regexp{
-	//Folders starting with .
-	matchFolders(fmt.Sprintf("%s.+?", regexp.QuoteMeta("."))),
-	matchFolders("AppData"),
-	matchFolders("Program Files"),
+func (b *invertedBool) String() string {
+	if b == nil {
+		return "false"
+	}
+	return strconv.FormatBool(!bool(*b))
 }
 
-var separatorEscaped = regexp.QuoteMeta(string(filepath.Separator))
-
-func matchFolders(folderName string) *regexp.Regexp {
-	regEx := fmt.Sprintf(".*?%s%s%s.*?",
-		separatorEscaped, folderName, separatorEscaped)
-
-	return regexp.MustCompile(regEx)
+func (b *invertedBool) Set(v string) error {
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*b = invertedBool(!parsed)
+	return nil
 }
 
+func (b *invertedBool) IsBoolFlag() bool { return true }
+
 func main() {
-	deleteFlag := flag.Bool("delete", false, "set to delete found folders")
-	flag.Parse()
+	if firstArg() == "schedule" {
+		if err := runSchedule(os.Args[2:]); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	c := newConfig(*deleteFlag)
-	results, err := run(c)
-	if err != nil {
+	c := newConfig()
+	defineFlags(flag.CommandLine, c)
+	flag.Usage = usage
+	if err := applyConfigFile(flag.CommandLine); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
 		os.Exit(1)
 	}
-
-	if len(results.folders) == 0 {
-		fmt.Printf("No results found\n")
-		return
+	c.fromDirs.override()
+	if err := applyEnv(flag.CommandLine); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		os.Exit(1)
 	}
+	c.fromDirs.override()
 
-	results.print()
-	if !c.delete {
-		fmt.Printf("Run with -delete to delete these folders")
-	} else {
-		for _, f := range results.folders {
-			fmt.Printf("Deleting %s...", f.path)
-			err := os.RemoveAll(f.path)
-			if err != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "error deleting %s: %s, exiting", f.path, err)
+	if cmd := findCommand(firstArg()); cmd != nil {
+		if cmd.name == "config" {
+			if err := printConfig(os.Stdout, flag.CommandLine); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
 				os.Exit(1)
 			}
-			fmt.Printf("OK\n")
+			return
+		}
+		if err := parseCommand(cmd, c, os.Args[2:]); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			os.Exit(1)
 		}
+		if cmd.name == "history" || cmd.name == "stats" || cmd.name == "restore" {
+			run := runHistory
+			if cmd.name == "stats" {
+				run = runStats
+			} else if cmd.name == "restore" {
+				run = runRestore
+			}
+			if err := run(os.Stdout, c); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+				os.Exit(1)
+			}
+			return
+		}
+	} else {
+		flag.Parse()
+		c.args = flag.Args()
 	}
-}
 
-func newResults() *Results {
-	return &Results{
-		folders: make([]*Folder, 0, DefaultLimit),
+	if c.fromPlan != "" {
+		if c.stdin || len(c.args) > 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "error: -from-plan can't be used with -stdin or folders listed after the flags")
+			os.Exit(1)
+		}
+		pl, err := readPlan(c.fromPlan)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			os.Exit(1)
+		}
+		pl.apply(c)
+	} else if c.stdin {
+		if len(c.args) > 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "error: -stdin can't be used with folders listed after the flags")
+			os.Exit(1)
+		}
+		paths, err := readPaths(os.Stdin)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: reading -stdin: %s", err)
+			os.Exit(1)
+		}
+		c.Paths = paths
+	} else if c.Exact {
+		if len(c.args) == 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "error: -exact needs the folders to look at listed after the flags or -stdin")
+			os.Exit(1)
+		}
+		c.Paths = c.args
+	} else {
+		for _, dir := range c.args {
+			_ = c.fromDirs.Set(dir)
+		}
+	}
+	// Paths found are compared with absolute ones, such as -exclude patterns
+	// and home folders, so the walk starts from absolute folders.
+	dirs := make([]string, 0, len(c.fromDirs.values()))
+	for _, dir := range c.fromDirs.values() {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		dirs = append(dirs, dir)
+	}
+	c.FromDir, c.ExtraDirs = dirs[0], dirs[1:]
+	if c.PerUser {
+		homes, err := cleaner.UserHomes()
+		if err == nil && len(homes) == 0 {
+			err = errors.New("no home folders")
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: -per-user: %s", err)
+			os.Exit(1)
+		}
+		c.userHomes = homes
 	}
-}
 
-type Results struct {
-	folders     []*Folder
-	totalSizeMb int
-}
+	presets := c.presets
+	if c.buildArtifacts {
+		if len(presets) == 0 && len(c.targetNames) == 0 {
+			presets = append(presets, cleaner.DefaultPreset)
+		}
+		presets = append(presets, cleaner.ArtifactsPreset)
+	}
+	targets, err := cleaner.BuildTargets(presets, c.targetNames)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		os.Exit(1)
+	}
+	c.Targets = targets
 
-func (r *Results) add(f *Folder) {
-	r.totalSizeMb += f.sizeMb
-	r.folders = append(r.folders, f)
-}
+	for _, pattern := range c.excludePatterns {
+		re, err := cleaner.CompileExclude(pattern)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: invalid -exclude %q: %s", pattern, err)
+			os.Exit(1)
+		}
+		c.Excludes = append(c.Excludes, re)
+	}
 
-func (r *Results) sort() {
-	sort.Slice(r.folders, func(i, j int) bool {
-		return r.folders[i].sizeMb > r.folders[j].sizeMb
-	})
-}
+	if !validFormat(c.format) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -format %q", c.format)
+		os.Exit(1)
+	}
+	if c.format == FormatJSON {
+		c.json = true
+	}
+	if c.format != FormatTable && c.format != FormatJSON && (c.json || c.porcelain || c.print0 || c.delete) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: -format %s cannot be used with -json, -porcelain, -print0 or -delete", c.format)
+		os.Exit(1)
+	}
 
-func (r *Results) print() {
-	longestPath := 0
-	for _, f := range r.folders {
-		if len(f.path) > longestPath {
-			longestPath = len(f.path)
-		}
+	if c.porcelain && (c.json || c.print0) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: -porcelain cannot be used with -json or -print0")
+		os.Exit(1)
 	}
 
-	longestPath++
+	if c.print0 && c.delete {
+		_, _ = fmt.Fprintf(os.Stderr, "error: -print0 cannot be used with -delete")
+		os.Exit(1)
+	}
 
-	fmtStringRows := "%-" + strconv.Itoa(longestPath) + "s|%20d|%15dMB\n"
-	fmtStringHead := "%-" + strconv.Itoa(longestPath) + "s|%20s|%17s\n"
+	failOn, err := parseFailOn(c.failOn)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		os.Exit(1)
+	}
+	c.failOnSet = failOn
 
-	fmt.Printf(fmtStringHead, "Path", "Modified Days Ago", "Size MB")
-	for _, f := range r.folders {
-		fmt.Printf(fmtStringRows, f.path, f.modDaysAgo, f.sizeMb)
+	if !cleaner.ValidAgeSource(c.AgeSource) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -age-source %q", c.AgeSource)
+		os.Exit(1)
 	}
-}
 
-type Folder struct {
-	path       string
-	sizeMb     int
-	modDaysAgo int
-}
+	if !validColor(c.color) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -color %q", c.color)
+		os.Exit(1)
+	}
+	if !validPathDisplay(c.pathDisplay) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -path-display %q", c.pathDisplay)
+		os.Exit(1)
+	}
+	c.table = newTableStyle(os.Stdout, c.color)
+	c.table.paths, c.table.roots = c.pathDisplay, c.startDirs()
+	if c.PerUser {
+		c.table.roots = c.userHomes
+	}
 
-type Config struct {
-	daysAgo   int
-	mbGreater int
-	limit     int
-	fromDir   string
-	delete    bool
-}
+	if c.CachesOnly && (c.Caches || c.Histogram) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: -caches-only can't be used with -caches or -histogram")
+		os.Exit(1)
+	}
 
-const (
-	DefaultLimit     = 10
-	DefaultMbGreater = 50
-	DefaultDaysAgo   = 7
-)
+	if !validLogFormat(c.logFormat) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -log-format %q", c.logFormat)
+		os.Exit(1)
+	}
 
-var DefaultStartDir = string(filepath.Separator)
+	if !validVerbosity(c.verbosity) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -verbosity %q", c.verbosity)
+		os.Exit(1)
+	}
 
-func newConfig(delete bool) *Config {
-	return &Config{
-		daysAgo:   DefaultDaysAgo,
-		mbGreater: DefaultMbGreater,
-		limit:     DefaultLimit,
-		fromDir:   DefaultStartDir,
-		delete:    delete,
+	if !cleaner.ValidSort(c.SortBy) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -sort %q", c.SortBy)
+		os.Exit(1)
+	}
+
+	if !cleaner.ValidArchiveCompress(c.ArchiveCompress) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -archive-compress %q", c.ArchiveCompress)
+		os.Exit(1)
+	}
+
+	if !cleaner.ValidDeleteOrder(c.DeleteOrder) {
+		_, _ = fmt.Fprintf(os.Stderr, "error: unknown -delete-order %q", c.DeleteOrder)
+		os.Exit(1)
 	}
-}
 
-var reachedMax = errors.New("reached max found")
+	if os.Geteuid() == 0 && !c.allowRoot {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", errRoot)
+		os.Exit(1)
+	}
 
-func run(c *Config) (*Results, error) {
-	results := newResults()
-	err := filepath.WalkDir(c.fromDir, func(path string, d fs.DirEntry, err error) error {
-		if !d.IsDir() {
-			return nil
+	if c.nice {
+		c.Workers, c.DeleteWorkers = 1, 1
+		if err := lowerPriority(); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: lowering priority for -nice: %s\n", err)
 		}
+	}
 
-		for _, excludePattern := range excludeFolders {
-			if excludePattern.MatchString(path) {
-				return fs.SkipDir
+	progress := newProgress(os.Stderr)
+	if !c.quiet() {
+		c.Progress = func(status string) {
+			if status == "" {
+				progress.clear()
+			} else {
+				progress.update("%s", status)
 			}
 		}
-
-		if filepath.Base(path) == NodeModules {
-			modDaysAgo, err := latestModifiedFile(filepath.Dir(path))
-			if err != nil {
-				return err
+	}
+	c.Warn = func(err error) {
+		c.warn(progress, err)
+	}
+	c.OnEvent = c.logEvents(progress)
+	if c.logFile != "" {
+		log, err := openRunLog(c.logFile, c.logFormat)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: opening -log-file: %s", err)
+			os.Exit(1)
+		}
+		c.runLog = log
+		log.record("start", "command", c.command, "args", redactArgs(os.Args[1:]))
+
+		printEvent := c.OnEvent
+		c.OnEvent = func(e cleaner.Event) {
+			log.event(c, e)
+			if printEvent != nil {
+				printEvent(e)
 			}
+		}
+	}
 
-			if modDaysAgo < c.daysAgo {
-				return fs.SkipDir
-			}
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
 
-			sizeMb, err := folderSizeMb(path)
-			if err != nil {
-				return err
-			}
+	if c.command == "projects" {
+		os.Exit(runProjects(ctx, os.Stdout, c))
+	}
 
-			if sizeMb < c.mbGreater {
-				return fs.SkipDir
-			}
+	locked := c.startDirs()
+	if c.PerUser {
+		locked = c.userHomes
+	}
+	if err := lockDirs(locked, c.forceUnlock); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		os.Exit(1)
+	}
 
-			folder := &Folder{
-				path:       path,
-				sizeMb:     sizeMb,
-				modDaysAgo: modDaysAgo,
-			}
+	if c.command == "serve" {
+		if err := runServe(c); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-			results.add(folder)
-			if len(results.folders) == c.limit {
-				return reachedMax
-			}
+	if c.watch {
+		if err := watch(ctx, c); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if resumePending(c) {
+		return
+	}
+
+	// Ctrl-C stops the scan but still shows what was found. It is only caught
+	// while scanning and deleting so it can still abort the prompts.
+	scanCtx, stopScan := signal.NotifyContext(ctx, os.Interrupt)
+	scanStart := time.Now()
+	results, err := cleaner.NewScanner(c.Options).Scan(scanCtx)
+	c.scanDuration = time.Since(scanStart)
+	stopped := err != nil && scanCtx.Err() != nil
+	stopScan()
+	c.runLog.scanned(results, err)
+	if results != nil && results.Partial {
+		_, _ = fmt.Fprintf(os.Stderr, "Partial results: the scan stopped after -max-duration %s, before it had looked everywhere\n", c.MaxDuration)
+	}
+
+	if err == nil {
+		c.recordScan(results)
+		c.writeMetrics(results, nil)
+		if !c.delete || len(results.Folders) == 0 {
+			c.notifyDone(results, nil)
+		}
+	}
+	if c.planOut != "" && (err == nil || stopped) {
+		if err := writePlan(c.planOut, results); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: writing plan: %s", err)
+			os.Exit(1)
+		}
+	}
+	if c.reportOut != "" && (err == nil || stopped) {
+		if err := writeHTMLReport(c.reportOut, c, results); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: writing report: %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if c.command == "analyze" {
+		if err != nil && !stopped {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			os.Exit(1)
+		}
+		os.Exit(runAnalyze(ctx, os.Stdout, c, results))
+	}
+
+	if c.json && !c.Histogram && !c.print0 {
+		os.Exit(runJSON(ctx, os.Stdin, os.Stdout, os.Stderr, c, results, err))
+	}
+	if c.porcelain && !c.Histogram {
+		os.Exit(runPorcelain(ctx, os.Stdin, os.Stdout, os.Stderr, c, results, err))
+	}
+	if stopped {
+		_, _ = fmt.Fprintf(os.Stderr, "%s, showing what was found so far\n", stopReason(ctx))
+	} else if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		os.Exit(1)
+	}
+
+	if c.Histogram {
+		printHistogram(results.Histogram())
+		printScanErrors(os.Stdout, results.Errors)
+		os.Exit(c.scanExitCode(results))
+	}
+
+	if c.print0 {
+		for _, f := range results.Folders {
+			fmt.Printf("%s\x00", f.Path)
+		}
+		printScanErrors(os.Stderr, results.Errors)
+		os.Exit(c.scanExitCode(results))
+	}
+
+	switch c.format {
+	case FormatMarkdown:
+		writeMarkdown(os.Stdout, c, results)
+	case FormatCSV, FormatTSV:
+		sep := ','
+		if c.format == FormatTSV {
+			sep = '\t'
+		}
+		if err := writeDelimited(os.Stdout, sep, results); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			os.Exit(1)
+		}
+	}
+	if c.format != FormatTable {
+		printScanErrors(os.Stderr, results.Errors)
+		os.Exit(c.scanExitCode(results))
+	}
 
-			return fs.SkipDir
+	if len(results.Folders) == 0 && len(results.Review) == 0 && len(results.Dirty) == 0 {
+		fmt.Printf("No results found\n")
+	}
+
+	if len(results.Review) > 0 {
+		fmt.Printf("Needs review, larger than %s and never deleted automatically:\n", cleaner.FormatSize(c.MaxSize))
+		printFolders(c.table, results.Review, false)
+		fmt.Printf("\n")
+	}
+
+	if len(results.Dirty) > 0 {
+		fmt.Printf("Skipped, uncommitted or unpushed changes in the project:\n")
+		printFolders(c.table, results.Dirty, false)
+		fmt.Printf("\n")
+	}
+
+	if len(results.Folders) == 0 {
+		printScanErrors(os.Stdout, results.Errors)
+		if !c.quiet() {
+			printScanStats(os.Stdout, results)
 		}
+		os.Exit(c.scanExitCode(results))
+	}
 
-		return nil
+	if c.FreeGoal > 0 {
+		if results.TotalReclaimable < c.FreeGoal {
+			fmt.Printf("Plan: all %d folders free %s, short of the %s goal\n",
+				len(results.Folders), cleaner.FormatSize(results.TotalReclaimable), cleaner.FormatSize(c.FreeGoal))
+		} else {
+			fmt.Printf("Plan: %d folders free %s, meeting the %s goal\n",
+				len(results.Folders), cleaner.FormatSize(results.TotalReclaimable), cleaner.FormatSize(c.FreeGoal))
+		}
+	}
+
+	if c.groupDepth > 0 {
+		groups, members := groupFolders(results.Folders, c.startDirs(), c.groupDepth, c.SortBy, c.Reverse)
+		printGroups(c.table, groups, members)
+	} else {
+		printFolders(c.table, results.Folders, true)
+	}
+	if c.PerUser {
+		printDirTotals(results.Folders, c.userHomes, true)
+	} else if len(c.ExtraDirs) > 0 {
+		printDirTotals(results.Folders, c.startDirs(), false)
+	}
+	if c.breakdown {
+		printBreakdowns(os.Stdout, results.Folders, breakdowns(ctx, c, results.Folders))
+	}
+	printScanErrors(os.Stdout, results.Errors)
+	if !c.quiet() {
+		printScanStats(os.Stdout, results)
+	}
+	if stopped {
+		fmt.Printf("The scan didn't finish, nothing deleted\n")
+		return
+	}
+	if !c.delete {
+		if c.planOut != "" {
+			c.printHint("Run clean -from-plan %s to delete these folders", c.planOut)
+		} else if c.command == "scan" {
+			c.printHint("Run clean with the same flags to delete these folders")
+		} else {
+			c.printHint("Run with -delete to delete these folders")
+		}
+		os.Exit(c.scanExitCode(results))
+	}
+
+	ok, err := chooseToDelete(os.Stdin, os.Stdout, results, c)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		os.Exit(1)
+	}
+	if !ok {
+		return
+	}
+
+	deleteCtx, stopDelete := signal.NotifyContext(ctx, os.Interrupt)
+	defer stopDelete()
+
+	var reclaimed int64
+	volumes := cleaner.MeasureVolumes(results.Folders)
+	c.recordRestore(results)
+	deleted := cleaner.Delete(deleteCtx, results, c.Options, func(r cleaner.DeleteResult) bool {
+		if r.Archive != "" {
+			fmt.Printf("Archived %s to %s\n", r.Path, r.Archive)
+		}
+		if r.Err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error deleting %s: %s\n", r.Path, r.Err)
+		} else if r.Trashed {
+			fmt.Printf("Moved %s to the trash\n", r.Path)
+		} else {
+			reclaimed += r.BytesFreed
+			fmt.Printf("Deleted %s, %s reclaimed so far\n", r.Path, cleaner.FormatSize(reclaimed))
+		}
+		return true
 	})
+	c.recordHistory(results, deleted)
+	c.writeMetrics(results, deleted)
+	c.notifyDone(results, deleted)
+	cleaner.FinishVolumes(volumes, deleted)
+	printVolumes(volumes)
+	c.printHint("Reclaimed %s of the %s found\n", cleaner.FormatSize(reclaimed), cleaner.FormatSize(results.TotalSize))
+
+	if len(deleted) < len(results.Folders) {
+		fmt.Printf("%s, stopped after %d of %d folders, %s reclaimed\n",
+			stopReason(ctx), len(deleted), len(results.Folders), cleaner.FormatSize(reclaimed))
+		fmt.Printf("Not deleted:\n")
+		printFolders(c.table, notDeleted(results.Folders, deleted), false)
+	}
+	if deleteFailed(deleted) {
+		printDeleteFailures(deleted)
+		os.Exit(c.exitCode(FailOnDeleteError))
+	}
+	os.Exit(c.scanExitCode(results))
+}
+
+// stopReason describes why a scan or deletion stopped early, given the
+// context that carries any -timeout.
+func stopReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "Timed out"
+	}
+	return "Interrupted"
+}
 
-	if err != nil && err != reachedMax {
-		return nil, err
+// notDeleted returns the folders that have no result in deleted.
+func notDeleted(folders []*cleaner.Folder, deleted []cleaner.DeleteResult) []*cleaner.Folder {
+	done := make(map[string]bool, len(deleted))
+	for _, r := range deleted {
+		done[r.Path] = true
 	}
 
-	results.sort()
-	return results, nil
+	var left []*cleaner.Folder
+	for _, f := range folders {
+		if !done[f.Path] {
+			left = append(left, f)
+		}
+	}
+	return left
+}
+
+// firstArg returns the first command line argument, which may be a command,
+// or "" if there are none.
+func firstArg() string {
+	if len(os.Args) < 2 {
+		return ""
+	}
+	return os.Args[1]
 }
 
-func latestModifiedFile(p string) (int, error) {
-	lastModified := time.Time{}
-	err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() {
-			if filepath.Base(path) == NodeModules {
-				return filepath.SkipDir
+// runJSON writes the scan results, and the outcome of any deletion, to stdout
+// as a single JSON document and returns the exit code. Before deleting, it
+// asks on stderr as the table output does, reading the answers from stdin.
+func runJSON(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, c *Config, results *cleaner.Result, scanErr error) int {
+	report := newJSONReport(results)
+	if scanErr != nil {
+		report.addError(scanErr)
+	} else if c.breakdown {
+		report.addBreakdowns(results.Folders, breakdowns(ctx, c, results.Folders))
+	}
+
+	code := ExitOK
+	if scanErr != nil {
+		code = ExitError
+	} else {
+		code = c.scanExitCode(results)
+	}
+	if scanErr == nil && c.delete && len(results.Folders) > 0 {
+		ok, err := chooseToDelete(stdin, stderr, results, c)
+		if err != nil {
+			report.addError(err)
+			code = ExitError
+		} else if ok {
+			deleteCtx, stopDelete := signal.NotifyContext(ctx, os.Interrupt)
+			volumes := cleaner.MeasureVolumes(results.Folders)
+			c.recordRestore(results)
+			deleted := cleaner.Delete(deleteCtx, results, c.Options, nil)
+			stopDelete()
+			cleaner.FinishVolumes(volumes, deleted)
+			report.addVolumes(volumes)
+			c.recordHistory(results, deleted)
+			c.writeMetrics(results, deleted)
+			c.notifyDone(results, deleted)
+
+			report.addDeleted(deleted)
+			if deleteFailed(deleted) {
+				code = c.exitCode(FailOnDeleteError)
 			}
-			return nil
 		}
+	}
 
-		info, err := d.Info()
-		if err != nil {
-			return err
+	if err := report.write(stdout); err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %s", err)
+		return ExitError
+	}
+	return code
+}
+
+func deleteFailed(deleted []cleaner.DeleteResult) bool {
+	for _, r := range deleted {
+		if r.Err != nil {
+			return true
 		}
+	}
+	return false
+}
 
-		modTime := info.ModTime()
-		if modTime.After(lastModified) {
-			lastModified = modTime
+// printFolders prints folders as a table in style s, fitted to the terminal
+// by shortening paths in the middle. With color, sizes are hotter the larger
+// they are, and with wouldDelete the paths are red. A Reclaimable column is
+// added when any folder shares hard linked files, so frees less than its size.
+func printFolders(s *tableStyle, folders []*cleaner.Folder, wouldDelete bool) {
+	longestPath := len("Total")
+	var totalSize, totalReclaimable int64
+	shared, managers, counted, scored := false, false, false, false
+	for _, f := range folders {
+		if n := len([]rune(s.displayPath(f))); n > longestPath {
+			longestPath = n
 		}
+		totalSize += f.SizeBytes
+		totalReclaimable += f.ReclaimableBytes
+		shared = shared || f.ReclaimableBytes != f.SizeBytes
+		managers = managers || f.PackageManager != ""
+		counted = counted || f.PackageCount > 0
+		scored = scored || f.Score > 0
+	}
 
-		return nil
-	})
+	// The Score, Packages, Reclaimable and Package Manager columns are only
+	// shown when they say something.
+	rest := 21 + 13
+	if scored {
+		rest += 7
+	}
+	if counted {
+		rest += 11
+	}
+	if shared {
+		rest += 13
+	}
+	if managers {
+		rest += 17
+	}
+	width := s.pathWidth(longestPath+1, rest)
 
-	if err != nil {
-		return -1, err
+	var pathColor []string
+	if wouldDelete {
+		pathColor = []string{ansiRed}
+	}
+	row := func(path string, pathColor []string, score, days, packages, size string, sizeColor []string, reclaimable, manager string) {
+		line := s.paint(fmt.Sprintf("%-"+strconv.Itoa(width)+"s", truncateMiddle(path, width-1)), pathColor...)
+		if scored {
+			line += fmt.Sprintf("|%6s", score)
+		}
+		line += fmt.Sprintf("|%20s", days)
+		if counted {
+			line += fmt.Sprintf("|%10s", packages)
+		}
+		line += "|" + s.paint(fmt.Sprintf("%12s", size), sizeColor...)
+		if shared {
+			line += fmt.Sprintf("|%12s", reclaimable)
+		}
+		if managers {
+			line += fmt.Sprintf("|%16s", manager)
+		}
+		fmt.Println(line)
 	}
 
-	return daysSince(lastModified), nil
+	var totalPackages int
+	row("Path", nil, "Score", "Modified Days Ago", "Packages", "Size", nil, "Reclaimable", "Package Manager")
+	for _, f := range folders {
+		row(s.displayPath(f), pathColor, strconv.FormatFloat(f.Score, 'f', 0, 64), groupThousands(f.ModDaysAgo), groupThousands(f.PackageCount),
+			cleaner.FormatSize(f.SizeBytes), sizeColor(f.SizeBytes), cleaner.FormatSize(f.ReclaimableBytes), f.PackageManager)
+		totalPackages += f.PackageCount
+	}
+	row("Total", []string{ansiBold}, "", "", groupThousands(totalPackages), cleaner.FormatSize(totalSize), []string{ansiBold}, cleaner.FormatSize(totalReclaimable), "")
 }
 
-func daysSince(t time.Time) int {
-	return int(time.Now().Unix()-t.Unix()) / 60 / 60 / 24
+// displayPath is how f is shown in the table, shortened as -path-display
+// says, with the number of workspace packages whose folders go with it.
+func (s *tableStyle) displayPath(f *cleaner.Folder) string {
+	if len(f.Members) == 0 {
+		return s.shorten(f.Path)
+	}
+	return fmt.Sprintf("%s (+%d workspace packages)", s.shorten(f.Path), len(f.Members))
 }
 
-func folderSizeMb(p string) (int, error) {
-	var sizeBytes int64
-	err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() {
-			return nil
+// printDirTotals prints how many folders were found below each start folder
+// and their total size. With users, the folders are users' home folders and
+// are shown by user name.
+func printDirTotals(folders []*cleaner.Folder, dirs []string, users bool) {
+	label := func(dir string) string { return dir }
+	heading := "By start folder"
+	if users {
+		label = filepath.Base
+		heading = "By user"
+	}
+
+	longestDir := 0
+	for _, dir := range dirs {
+		if len(label(dir)) > longestDir {
+			longestDir = len(label(dir))
 		}
+	}
 
-		info, err := d.Info()
-		if err != nil {
-			return err
+	fmt.Printf("\n%s:\n", heading)
+	for _, dir := range dirs {
+		count := 0
+		var size int64
+		for _, f := range folders {
+			if isBelow(dir, f.Path) {
+				count++
+				size += f.SizeBytes
+			}
+		}
+		fmt.Printf("  %-*s %6d folders %12s\n", longestDir, label(dir), count, cleaner.FormatSize(size))
+	}
+}
+
+// startDirOf returns the deepest of dirs that path is below, or "" if none.
+func startDirOf(dirs []string, path string) string {
+	start := ""
+	for _, dir := range dirs {
+		if isBelow(dir, path) && len(dir) > len(start) {
+			start = dir
 		}
+	}
+	return start
+}
 
-		sizeBytes += info.Size()
-		return nil
-	})
+// isBelow reports whether path is below dir.
+func isBelow(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
 
-	if err != nil {
-		return 0, err
+// printVolumes prints the free space actually gained on each volume folders
+// were deleted from, pointing out where it is far from the folder sizes.
+func printVolumes(volumes []*cleaner.Volume) {
+	for _, v := range volumes {
+		if v.Err != nil {
+			continue
+		}
+		fmt.Printf("Free space on %s went up by %s, folder sizes estimated %s", v.Path, cleaner.FormatSize(v.Freed()), cleaner.FormatSize(v.Estimated))
+		if v.Mismatched() {
+			fmt.Printf(", the difference may be from small files taking more disk than their size (see -disk-usage), hard links, files still open, failed deletions or other programs writing to the disk")
+		}
+		fmt.Printf("\n")
 	}
+}
 
-	return bytesToMb(sizeBytes), nil
+// printDeleteFailures lists the folders that couldn't be fully removed and
+// why.
+func printDeleteFailures(deleted []cleaner.DeleteResult) {
+	var failed []cleaner.DeleteResult
+	for _, r := range deleted {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+
+	fmt.Printf("\n%d folders couldn't be fully deleted:\n", len(failed))
+	for _, r := range failed {
+		fmt.Printf("  %s: %s\n", r.Path, r.Err)
+	}
 }
 
-func bytesToMb(b int64) int {
-	return int(b / 1024 / 1024)
+// printScanStats prints what the scan did, so a scan that found nothing can be
+// told apart from one that hardly looked, then how many were skipped for each
+// reason.
+func printScanStats(w io.Writer, results *cleaner.Result) {
+	s := results.Stats
+	scanned := "Scanned"
+	if results.Partial {
+		scanned = "Partial scan of"
+	}
+	_, _ = fmt.Fprintf(w, "%s %s folders in %s: %s target folders found, %s to delete taking %s, %s skipped, %s errors\n",
+		scanned, groupThousands(s.FoldersVisited), s.Duration.Round(time.Millisecond), groupThousands(s.TargetsSeen),
+		groupThousands(s.Candidates), cleaner.FormatSize(results.TotalSize), groupThousands(s.TotalSkipped()), groupThousands(s.Errors))
+
+	reasons := s.SkipReasons()
+	if len(reasons) == 0 {
+		return
+	}
+	counts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		counts[i] = fmt.Sprintf("%s %s", groupThousands(s.Skipped[reason]), reason)
+	}
+	_, _ = fmt.Fprintf(w, "Skipped: %s\n", strings.Join(counts, ", "))
+}
+
+// printScanErrors lists the folders skipped because they couldn't be read.
+func printScanErrors(w io.Writer, errs []*cleaner.ScanError) {
+	if len(errs) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\n%d folders were skipped because of errors:\n", len(errs))
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(w, "  %s\n", err)
+	}
+	_, _ = fmt.Fprintf(w, "\n")
+}
+
+// groupThousands formats n with a comma between each group of three digits.
+func groupThousands(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+
+	return sign + digits
+}
+
+// startDirs returns the absolute start folders, -from and those listed after
+// the flags, which the paths found are below.
+func (c *Config) startDirs() []string {
+	return append([]string{c.FromDir}, c.ExtraDirs...)
+}
+
+var errRoot = errors.New("running as root scans and deletes every user's folders, run with -allow-root if that is what you want")
+
+type Config struct {
+	cleaner.Options
+
+	delete      bool
+	confirmOver int64
+	yes         bool
+	print0      bool
+	json        bool
+	porcelain   bool
+	interactive bool
+	color       string
+	pathDisplay string
+	verbosity   string
+	table       *tableStyle
+	confirmEach bool
+	watch       bool
+	timeout     time.Duration
+	command     string
+	failOn      string
+	failOnSet   map[string]bool
+
+	targetNames     stringList
+	presets         stringList
+	excludePatterns stringList
+	fromDirs        dirList
+	args            []string
+	stdin           bool
+	buildArtifacts  bool
+	planOut         string
+	fromPlan        string
+	reportOut       string
+	format          string
+	metricsFile     string
+	logFile         string
+	logFormat       string
+	runLog          *runLog
+	addr            string
+	token           string
+	notify          bool
+	notifyURL       string
+	notifyUsers     bool
+	userHomes       []string
+	nice            bool
+	forceUnlock     bool
+	allowRoot       bool
+	rollbackPending bool
+	scanDuration    time.Duration
+	history         bool
+	historySince    time.Duration
+	restorePrint    bool
+	breakdown       bool
+	groupDepth      int
+	analyzeTop      int
+}
+
+func newConfig() *Config {
+	c := &Config{
+		Options:     cleaner.DefaultOptions(),
+		failOn:      FailOnFound + "," + FailOnDeleteError + "," + FailOnScanError,
+		history:     true,
+		format:      FormatTable,
+		color:       ColorAuto,
+		pathDisplay: PathsFull,
+		verbosity:   VerbosityNormal,
+		logFormat:   LogFormatJSON,
+		analyzeTop:  20,
+		addr:        "127.0.0.1:8080",
+	}
+	c.fromDirs.def = c.FromDir
+	return c
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// TestMain keeps the history, restore list and journal the tests write out
+// of the real user folders.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "npm-cleaner-home")
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range []string{"XDG_CACHE_HOME", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "HOME", "LocalAppData", "AppData"} {
+		_ = os.Setenv(name, home)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// projects creates a project below a new temporary folder for each name,
+// with a node_modules folder holding a file, and returns the folder and a
+// Result listing the node_modules folders as candidates.
+func projects(t *testing.T, names ...string) (string, *cleaner.Result) {
+	t.Helper()
+	root := t.TempDir()
+	var folders []*cleaner.Folder
+	for _, name := range names {
+		project := filepath.Join(root, name)
+		p := filepath.Join(project, cleaner.NodeModules)
+		if err := os.MkdirAll(filepath.Join(p, "left-pad"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for file, data := range map[string]string{
+			filepath.Join(project, "package.json"):   "{}",
+			filepath.Join(p, "left-pad", "index.js"): "x",
+		} {
+			if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		folders = append(folders, &cleaner.Folder{
+			Path:             p,
+			Project:          project,
+			SizeBytes:        1,
+			ReclaimableBytes: 1,
+			ModTime:          time.Now().Add(-30 * cleaner.Day),
+			ModDaysAgo:       30,
+		})
+	}
+	results := &cleaner.Result{}
+	results.Keep(folders)
+	return root, results
+}
+
+// testConfig returns the defaults, set to delete below root without
+// recording history.
+func testConfig(root string) *Config {
+	c := newConfig()
+	c.FromDir = root
+	c.LockRetries = 0
+	c.history = false
+	c.delete = true
+	return c
+}
+
+// remaining returns the names of the projects below root whose node_modules
+// folder is still there.
+func remaining(t *testing.T, root string, names ...string) []string {
+	t.Helper()
+	var left []string
+	for _, name := range names {
+		if _, err := os.Lstat(filepath.Join(root, name, cleaner.NodeModules)); err == nil {
+			left = append(left, name)
+		}
+	}
+	return left
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// resumePending deals with deletion runs that were killed. What each got done
+// is shown once from its journal. Folders it renamed but hadn't started
+// removing are put back, to be found again by the scan, and finishing the
+// ones it was part way through removing is offered. With -rollback-pending
+// all of them are put back instead and it returns true, as there is nothing
+// else to do.
+func resumePending(c *Config) bool {
+	for _, run := range cleaner.InterruptedRuns() {
+		printInterruptedRun(os.Stderr, run)
+		cleaner.ClearInterruptedRun(run)
+	}
+
+	pending := cleaner.PendingDeletions()
+	if c.rollbackPending {
+		if len(pending) == 0 {
+			fmt.Printf("No interrupted deletions to roll back\n")
+			return true
+		}
+		printRollback(cleaner.RollbackPending(pending))
+		return true
+	}
+
+	var partial, unstarted []cleaner.PendingDeletion
+	for _, e := range pending {
+		if e.Removing {
+			partial = append(partial, e)
+		} else {
+			unstarted = append(unstarted, e)
+		}
+	}
+	printRollback(cleaner.RollbackPending(unstarted))
+	if len(partial) == 0 {
+		return false
+	}
+
+	ok := c.yes
+	if !ok && isInteractive(os.Stdin) {
+		_, _ = fmt.Fprintf(os.Stderr, "Finish removing the %d partly removed folders? [y/N] ", len(partial))
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "\n")
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		ok = answer == "y" || answer == "yes"
+	}
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "%d partly removed folders are left from an interrupted run, run with -yes to finish removing them or -rollback-pending to put them back\n", len(partial))
+		return false
+	}
+
+	for _, r := range cleaner.FinishPending(partial, c.Options) {
+		if r.Err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error deleting %s: %s\n", r.Path, r.Err)
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Deleted %s\n", r.Path)
+		}
+	}
+	return false
+}
+
+// printInterruptedRun shows what happened to each folder a killed run set out
+// to delete.
+func printInterruptedRun(w io.Writer, run cleaner.Journal) {
+	_, _ = fmt.Fprintf(w, "The deletion run started %s was killed before it finished:\n", run.Started.Format("2006-01-02 15:04"))
+	for _, f := range run.Folders {
+		state := "not started"
+		switch f.State {
+		case cleaner.JournalRemoving:
+			state = "partly removed"
+		case cleaner.JournalRemoved:
+			state = "removed"
+		case cleaner.JournalFailed:
+			state = "failed"
+		}
+		_, _ = fmt.Fprintf(w, "  %-14s  %s  %s\n", state, f.Path, cleaner.FormatSize(f.SizeBytes))
+	}
+	_, _ = fmt.Fprintf(w, "\n")
+}
+
+func printRollback(restored []cleaner.DeleteResult) {
+	for _, r := range restored {
+		if r.Err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error rolling back %s: %s\n", r.Path, r.Err)
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Restored %s\n", r.Path)
+		}
+	}
+}
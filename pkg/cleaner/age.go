@@ -0,0 +1,95 @@
+package cleaner
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+const (
+	AgeSourceProject = "project"
+	AgeSourceTarget  = "target"
+	AgeSourceGit     = "git"
+)
+
+func ValidAgeSource(source string) bool {
+	switch source {
+	case AgeSourceProject, AgeSourceTarget, AgeSourceGit:
+		return true
+	}
+	return false
+}
+
+// projectModTime returns when the project owning the target folder at path
+// was last modified, according to -age-source. For project, the default, it
+// is the newest file in the project outside of target and .git folders, as
+// a target folder's own time often doesn't change while the source is being
+// worked on. For target it is the target folder's modified time. For git it
+// is the last commit, falling back to project if the project isn't a repo or
+// the scan isn't of the real filesystem.
+func projectModTime(o *Options, project, path string) (time.Time, error) {
+	switch {
+	case o.AgeSource == AgeSourceTarget:
+		info, err := o.files().Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return info.ModTime(), nil
+	case o.AgeSource == AgeSourceGit && o.real() && isGitRepo(project):
+		return lastCommitTime(project)
+	}
+
+	return latestModifiedFile(o.files(), project, o.Targets)
+}
+
+// ParseAge parses an age such as 30d, 2w, 1.5d or 12h. Days and weeks are
+// added to the units understood by time.ParseDuration, and a number on its own
+// is in days.
+func ParseAge(s string) (time.Duration, error) {
+	v := strings.TrimSpace(s)
+	if days, err := strconv.ParseFloat(v, 64); err == nil {
+		return scaleAge(days, Day, s)
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": Day, "w": Week} {
+		if n, err := strconv.ParseFloat(strings.TrimSuffix(v, suffix), 64); strings.HasSuffix(v, suffix) && err == nil {
+			return scaleAge(n, unit, s)
+		}
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// scaleAge returns n of unit, refusing ages that are negative, not finite,
+// such as "inf" and "nan", or too long for a time.Duration.
+func scaleAge(n float64, unit time.Duration, s string) (time.Duration, error) {
+	if n < 0 || math.IsInf(n, 0) || math.IsNaN(n) || n*float64(unit) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+// FormatAge formats d in whole weeks, days or hours where it divides evenly,
+// falling back to time.Duration's format.
+func FormatAge(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "0"
+	case d%Week == 0:
+		return strconv.FormatInt(int64(d/Week), 10) + "w"
+	case d%Day == 0:
+		return strconv.FormatInt(int64(d/Day), 10) + "d"
+	}
+	return d.String()
+}
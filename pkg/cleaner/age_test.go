@@ -0,0 +1,39 @@
+package cleaner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30", want: 30 * Day},
+		{in: "30d", want: 30 * Day},
+		{in: "2w", want: 2 * Week},
+		{in: "1.5d", want: 36 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: " 7d ", want: 7 * Day},
+		{in: "-1d", wantErr: true},
+		{in: "inf", wantErr: true},
+		{in: "+Inf", wantErr: true},
+		{in: "infd", wantErr: true},
+		{in: "nan", wantErr: true},
+		{in: "NaNw", wantErr: true},
+		{in: "1e30d", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAge(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAge(%q) err = %v, want error %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
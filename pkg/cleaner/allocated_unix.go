@@ -0,0 +1,20 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// allocatedSize returns the space on disk taken by the file described by
+// info, which is whole blocks and so usually more than its size.
+func allocatedSize(path string, info fs.FileInfo) (int64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	// st_blocks is always in 512 byte units, whatever the filesystem's block
+	// size.
+	return int64(st.Blocks) * 512, true
+}
@@ -0,0 +1,30 @@
+package cleaner
+
+import (
+	"io/fs"
+	"syscall"
+	"unsafe"
+)
+
+var procGetCompressedFileSizeW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetCompressedFileSizeW")
+
+// allocatedSize returns the space on disk taken by the file at path, which
+// is less than its size if it is compressed or sparse. Windows doesn't report
+// it in info, so it needs a call per file.
+func allocatedSize(path string, info fs.FileInfo) (int64, bool) {
+	if _, ok := info.Sys().(*syscall.Win32FileAttributeData); !ok {
+		return 0, false
+	}
+
+	name, err := syscall.UTF16PtrFromString(longPath(path))
+	if err != nil {
+		return 0, false
+	}
+
+	var high uint32
+	low, _, callErr := procGetCompressedFileSizeW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&high)))
+	if uint32(low) == 0xFFFFFFFF && callErr != syscall.Errno(0) {
+		return 0, false
+	}
+	return int64(high)<<32 | int64(uint32(low)), true
+}
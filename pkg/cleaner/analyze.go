@@ -0,0 +1,185 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageCopies is one version of a package and every copy of it installed
+// in the folders analysed.
+type PackageCopies struct {
+	Name    string
+	Version string
+	// Folders is how many of the folders analysed have it installed, and
+	// Copies how many copies there are, which is more when it is nested
+	// inside other packages.
+	Folders int
+	Copies  int
+	// SizeBytes is the size of the largest copy, not counting any packages
+	// nested in its own node_modules, and TotalBytes of them all.
+	SizeBytes  int64
+	TotalBytes int64
+}
+
+// DuplicateBytes is the space taken by all but one copy, which a package
+// manager with a shared store, such as pnpm, wouldn't use.
+func (p *PackageCopies) DuplicateBytes() int64 {
+	return p.TotalBytes - p.SizeBytes
+}
+
+// AnalyzePackages finds every package installed in the node_modules folders
+// in folders, at any depth, and returns each version of each with how many
+// copies there are and their size, those taking most space in duplicate
+// copies first. Folders that aren't node_modules are skipped.
+func AnalyzePackages(ctx context.Context, opts Options, folders []*Folder) ([]*PackageCopies, error) {
+	o := &opts
+	fsys := o.files()
+	byVersion := make(map[string]*PackageCopies)
+	for i, f := range folders {
+		if filepath.Base(f.Path) != "node_modules" {
+			continue
+		}
+		o.progress("Analysing %d of %d: %s", i+1, len(folders), f.Path)
+
+		seen := make(map[*PackageCopies]bool)
+		err := walkPackages(ctx, fsys, f.Path, func(name, version, path string) error {
+			size, err := packageSize(ctx, fsys, path, o.DiskUsage)
+			if err != nil {
+				return err
+			}
+			key := name + "@" + version
+			p := byVersion[key]
+			if p == nil {
+				p = &PackageCopies{Name: name, Version: version}
+				byVersion[key] = p
+			}
+			if size > p.SizeBytes {
+				p.SizeBytes = size
+			}
+			if !seen[p] {
+				seen[p] = true
+				p.Folders++
+			}
+			p.Copies++
+			p.TotalBytes += size
+			return nil
+		})
+		if err != nil && ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	o.progressDone()
+
+	packages := make([]*PackageCopies, 0, len(byVersion))
+	for _, p := range byVersion {
+		packages = append(packages, p)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].DuplicateBytes() != packages[j].DuplicateBytes() {
+			return packages[i].DuplicateBytes() > packages[j].DuplicateBytes()
+		}
+		return packages[i].Name+"@"+packages[i].Version < packages[j].Name+"@"+packages[j].Version
+	})
+	return packages, nil
+}
+
+// walkPackages calls found with each package in the node_modules folder at
+// dir, including scoped ones, those nested in other packages' node_modules
+// and those in pnpm's .pnpm store. Folders without a readable package.json
+// with a name and version are skipped.
+func walkPackages(ctx context.Context, fsys fileSystem, dir string, found func(name, version, path string) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() || isLink(e) {
+			continue
+		}
+		name := e.Name()
+		p := filepath.Join(dir, name)
+		switch {
+		case name == ".pnpm":
+			store, err := fsys.ReadDir(p)
+			if err != nil {
+				continue
+			}
+			for _, s := range store {
+				if s.IsDir() && !isLink(s) && s.Name() != "node_modules" {
+					if err := walkPackages(ctx, fsys, filepath.Join(p, s.Name(), "node_modules"), found); err != nil {
+						return err
+					}
+				}
+			}
+		case strings.HasPrefix(name, "."):
+		case strings.HasPrefix(name, "@"):
+			if err := walkPackages(ctx, fsys, p, found); err != nil {
+				return err
+			}
+		default:
+			if err := visitPackage(ctx, fsys, p, found); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func visitPackage(ctx context.Context, fsys fileSystem, p string, found func(name, version, path string) error) error {
+	data, err := fsys.ReadFile(filepath.Join(p, "package.json"))
+	if err == nil {
+		var pkg struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(data, &pkg) == nil && pkg.Name != "" && pkg.Version != "" {
+			if err := found(pkg.Name, pkg.Version, p); err != nil {
+				return err
+			}
+		}
+	}
+	return walkPackages(ctx, fsys, filepath.Join(p, "node_modules"), found)
+}
+
+// packageSize adds up the size of the files in the package at p, leaving out
+// the packages in its own node_modules.
+func packageSize(ctx context.Context, fsys fileSystem, p string, allocated bool) (int64, error) {
+	nested := filepath.Join(p, "node_modules")
+	var size int64
+	err := walkDir(fsys, p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == nested {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		n := info.Size()
+		if allocated {
+			if a, ok := allocatedSize(path, info); ok {
+				n = a
+			}
+		}
+		size += n
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,326 @@
+package cleaner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// How archives made by Options.ArchiveDir are compressed.
+const (
+	ArchiveNone = "none"
+	ArchiveGzip = "gzip"
+	ArchiveZstd = "zstd"
+)
+
+func ValidArchiveCompress(compress string) bool {
+	switch compress {
+	case ArchiveNone, ArchiveGzip, ArchiveZstd:
+		return true
+	}
+	return false
+}
+
+var archiveExts = map[string]string{
+	ArchiveNone: ".tar",
+	ArchiveGzip: ".tar.gz",
+	ArchiveZstd: ".tar.zst",
+}
+
+var errArchiveNotReal = errors.New("folders can only be archived from the real filesystem")
+
+// archiveBase returns the name, without extension, of the archive of the
+// folder at path in dir. It is made from the folder's project and name, which
+// are easy to recognise, and a hash of its whole path so folders with the
+// same names don't collide.
+func archiveBase(dir, path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(path))
+	name := filepath.Base(filepath.Dir(path)) + "-" + filepath.Base(path) + "-" + hex.EncodeToString(sum[:4])
+	return filepath.Join(dir, strings.TrimLeft(name, "."))
+}
+
+// FindArchive returns the archive of the folder at path in dir, or "" if
+// there isn't one.
+func FindArchive(dir, path string) string {
+	base := archiveBase(dir, path)
+	for _, compress := range []string{ArchiveZstd, ArchiveGzip, ArchiveNone} {
+		if _, err := os.Stat(base + archiveExts[compress]); err == nil {
+			return base + archiveExts[compress]
+		}
+	}
+	return ""
+}
+
+// archiveFolder writes the folder at path, which was at original before
+// being released, to a tar archive in o.ArchiveDir and returns the archive's
+// path. Entries are named from the folder's own name, e.g. node_modules/...,
+// so it extracts back into the project. The archive is written under a
+// temporary name and renamed once complete, so a failed or interrupted one is
+// never mistaken for a whole one.
+func archiveFolder(o *Options, path, original string) (string, error) {
+	compress := o.ArchiveCompress
+	if compress == "" {
+		compress = ArchiveNone
+	}
+	if err := os.MkdirAll(o.ArchiveDir, 0o755); err != nil {
+		return "", err
+	}
+	dest := archiveBase(o.ArchiveDir, original) + archiveExts[compress]
+
+	f, err := os.CreateTemp(o.ArchiveDir, ".archive-*")
+	if err != nil {
+		return "", err
+	}
+	err = writeArchive(f, compress, path, filepath.Base(original))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), dest)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("archiving: %w", err)
+	}
+	return dest, nil
+}
+
+func writeArchive(w io.Writer, compress, path, name string) error {
+	var zstd *exec.Cmd
+	var closer io.Closer
+	switch compress {
+	case ArchiveGzip:
+		gz := gzip.NewWriter(w)
+		w, closer = gz, gz
+	case ArchiveZstd:
+		zstd = exec.Command("zstd", "-q", "-c", "-")
+		zstd.Stdout = w
+		in, err := zstd.StdinPipe()
+		if err != nil {
+			return err
+		}
+		if err := zstd.Start(); err != nil {
+			return fmt.Errorf("zstd: %w", err)
+		}
+		w, closer = in, in
+	}
+
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(longPath(path), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return addToArchive(tw, p, name+strings.TrimPrefix(p, longPath(path)), d)
+	})
+	if closeErr := tw.Close(); err == nil {
+		err = closeErr
+	}
+	if closer != nil {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if zstd != nil {
+		if waitErr := zstd.Wait(); err == nil && waitErr != nil {
+			err = fmt.Errorf("zstd: %w", waitErr)
+		}
+	}
+	return err
+}
+
+// addToArchive adds the file or folder at p to tw as name. Links are kept
+// as links, as node_modules/.bin is full of them.
+func addToArchive(tw *tar.Writer, p, name string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.ToSlash(name)
+	if d.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ExtractArchive extracts the archive of the folder at path, as found by
+// FindArchive, back to where it was. Nothing may be there already.
+func ExtractArchive(archive, path string) error {
+	if _, err := os.Lstat(path); err == nil {
+		return &fs.PathError{Op: "extract", Path: path, Err: fs.ErrExist}
+	}
+
+	// Extract next to the folder and rename it into place once complete.
+	tmp, err := os.MkdirTemp(filepath.Dir(path), "."+filepath.Base(path)+".extracting-")
+	if err != nil {
+		return err
+	}
+	if err := extractArchive(archive, tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	name := filepath.Join(tmp, filepath.Base(path))
+	if _, err := os.Lstat(name); err != nil {
+		_ = os.RemoveAll(tmp)
+		return fmt.Errorf("%s doesn't hold %s", archive, filepath.Base(path))
+	}
+	err = os.Rename(name, path)
+	_ = os.RemoveAll(tmp)
+	return err
+}
+
+// extractArchive extracts archive, decompressing it as its name says, to dir.
+func extractArchive(archive, dir string) (err error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(archive, archiveExts[ArchiveGzip]):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(archive, archiveExts[ArchiveZstd]):
+		zstd := exec.Command("zstd", "-q", "-d", "-c", "-")
+		zstd.Stdin = f
+		out, err := zstd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := zstd.Start(); err != nil {
+			return fmt.Errorf("zstd: %w", err)
+		}
+		defer func() {
+			// zstd exits only once what it writes has been read, so stop
+			// it if extracting failed, or else read what follows the end of
+			// the archive.
+			if err != nil {
+				_ = zstd.Process.Kill()
+			} else {
+				_, _ = io.Copy(io.Discard, out)
+			}
+			if werr := zstd.Wait(); werr != nil && err == nil {
+				err = fmt.Errorf("zstd: %w", werr)
+			}
+		}()
+		r = out
+	}
+
+	return extractTar(tar.NewReader(r), dir)
+}
+
+func extractTar(tr *tar.Reader, dir string) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/")))
+		if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q is outside the folder", hdr.Name)
+		}
+		if err := checkNotBelowLink(dir, name); err != nil {
+			return fmt.Errorf("archive entry %q: %w", hdr.Name, err)
+		}
+		p := filepath.Join(dir, name)
+		mode := fs.FileMode(hdr.Mode).Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, mode|0o700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, p); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, p, mode); err != nil {
+				return err
+			}
+		}
+		if hdr.Typeflag != tar.TypeSymlink {
+			_ = os.Chtimes(p, hdr.ModTime, hdr.ModTime)
+		}
+	}
+}
+
+// checkNotBelowLink returns an error if a folder above name in dir is a
+// symbolic link, as one extracted earlier from the same archive could point
+// outside dir and what is below it would be written there.
+func checkNotBelowLink(dir, name string) error {
+	p := dir
+	for _, part := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a link, not a folder", p)
+		}
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, p string, mode fs.FileMode) error {
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
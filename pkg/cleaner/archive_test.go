@@ -0,0 +1,182 @@
+package cleaner
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// tarEntry is an entry of a hand-built archive: a folder if its name ends in
+// "/", a link if link is set and a file of size bytes, or 1 byte, otherwise.
+type tarEntry struct {
+	name string
+	link string
+	size int
+}
+
+func buildTar(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(e.size)}
+		if hdr.Size == 0 {
+			hdr.Size = 1
+		}
+		switch {
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		case e.name[len(e.name)-1] == '/':
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0o755, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write(bytes.Repeat([]byte("x"), int(hdr.Size))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating links needs extra privileges on Windows")
+	}
+
+	tests := []struct {
+		name    string
+		entries func(outside string) []tarEntry
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "files and folders",
+			entries: func(string) []tarEntry {
+				return []tarEntry{{name: "node_modules/"}, {name: "node_modules/a/"}, {name: "node_modules/a/index.js"}}
+			},
+			want: []string{"node_modules/a/index.js"},
+		},
+		{
+			name: "a link to a folder in the archive",
+			entries: func(string) []tarEntry {
+				return []tarEntry{{name: "node_modules/"}, {name: "node_modules/a/"}, {name: "node_modules/b", link: "a"}}
+			},
+			want: []string{"node_modules/a", "node_modules/b"},
+		},
+		{
+			name: "outside the folder",
+			entries: func(string) []tarEntry {
+				return []tarEntry{{name: "../x"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "a file below a link outside the folder",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{{name: "node_modules/"}, {name: "node_modules/a", link: outside}, {name: "node_modules/a/x"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "a folder below a link outside the folder",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{{name: "a", link: outside}, {name: "a/b/"}}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			outside := t.TempDir()
+
+			err := extractTar(tar.NewReader(buildTar(t, tt.entries(outside)...)), dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want an error %t", err, tt.wantErr)
+			}
+			for _, name := range tt.want {
+				if _, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					t.Errorf("%s not extracted: %v", name, err)
+				}
+			}
+
+			written, err := os.ReadDir(outside)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(written) > 0 {
+				t.Errorf("%d entries written outside the folder", len(written))
+			}
+		})
+	}
+}
+
+func TestExtractArchiveZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd isn't installed")
+	}
+
+	// Entries larger than a pipe holds, so zstd is still writing when an
+	// entry is refused.
+	const size = 1 << 20
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{
+			name:    "extracted",
+			entries: []tarEntry{{name: "node_modules/"}, {name: "node_modules/big", size: size}},
+		},
+		{
+			name:    "an entry outside the folder",
+			entries: []tarEntry{{name: "../x"}, {name: "node_modules/big", size: size}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			archive := filepath.Join(root, "archive"+archiveExts[ArchiveZstd])
+			zstd := exec.Command("zstd", "-q", "-c", "-")
+			zstd.Stdin = buildTar(t, tt.entries...)
+			data, err := zstd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(archive, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- ExtractArchive(archive, filepath.Join(root, NodeModules)) }()
+			select {
+			case err = <-done:
+			case <-time.After(30 * time.Second):
+				t.Fatal("extracting didn't finish")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want an error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				info, err := os.Stat(filepath.Join(root, NodeModules, "big"))
+				if err != nil || info.Size() != size {
+					t.Errorf("big = %v, %v, want %d bytes", info, err, size)
+				}
+			}
+		})
+	}
+}
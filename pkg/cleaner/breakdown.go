@@ -0,0 +1,76 @@
+package cleaner
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultBreakdownTop is how many packages Breakdown returns by default.
+const DefaultBreakdownTop = 10
+
+// PackageSize is the size of something directly inside a found folder: a
+// package, or a folder such as .cache or .bin.
+type PackageSize struct {
+	Name      string
+	SizeBytes int64
+}
+
+// Breakdown sizes what is directly inside the folder at path and returns the
+// n largest, largest first. In node_modules, scoped packages are each sized
+// on their own as @scope/name, and pnpm's .pnpm store is broken down by
+// package too, so a single large package, such as a bundled browser, or a
+// build cache stands out. Links and empty folders are left out.
+func Breakdown(ctx context.Context, opts Options, path string, n int) ([]PackageSize, error) {
+	fsys := opts.files()
+	var names []string
+	if err := breakdownNames(fsys, path, "", &names); err != nil {
+		return nil, err
+	}
+
+	sizes := make([]PackageSize, 0, len(names))
+	for _, name := range names {
+		u, err := folderSize(ctx, fsys, filepath.Join(path, filepath.FromSlash(name)), opts.DiskUsage)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		if u.SizeBytes > 0 {
+			sizes = append(sizes, PackageSize{Name: name, SizeBytes: u.SizeBytes})
+		}
+	}
+
+	sort.SliceStable(sizes, func(i, j int) bool { return sizes[i].SizeBytes > sizes[j].SizeBytes })
+	if n > 0 && len(sizes) > n {
+		sizes = sizes[:n]
+	}
+	return sizes, nil
+}
+
+// breakdownNames adds the names of what is to be sized inside dir, below
+// path, to names.
+func breakdownNames(fsys fileSystem, path, dir string, names *[]string) error {
+	entries, err := fsys.ReadDir(filepath.Join(path, filepath.FromSlash(dir)))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if isLink(e) {
+			continue
+		}
+		name := e.Name()
+		if dir != "" {
+			name = dir + "/" + name
+		}
+		if e.IsDir() && dir == "" && (strings.HasPrefix(name, "@") || name == ".pnpm") {
+			if err := breakdownNames(fsys, path, name, names); err == nil {
+				continue
+			}
+		}
+		*names = append(*names, name)
+	}
+	return nil
+}
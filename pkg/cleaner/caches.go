@@ -0,0 +1,136 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// cacheFolders returns the usual locations of the global npm, npx, yarn and
+// pnpm caches for the current platform. They may not all exist.
+func cacheFolders() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	folders := []string{
+		filepath.Join(home, ".npm", "_cacache"),
+		filepath.Join(home, ".npm", "_npx"),
+		filepath.Join(home, ".yarn", "cache"),
+		filepath.Join(home, ".yarn", "berry", "cache"),
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		local := os.Getenv("LOCALAPPDATA")
+		folders = append(folders,
+			filepath.Join(os.Getenv("APPDATA"), "npm-cache", "_cacache"),
+			filepath.Join(os.Getenv("APPDATA"), "npm-cache", "_npx"),
+			filepath.Join(local, "Yarn", "Cache"),
+			filepath.Join(local, "pnpm", "store"),
+		)
+	case "darwin":
+		folders = append(folders,
+			filepath.Join(home, "Library", "Caches", "Yarn"),
+			filepath.Join(home, "Library", "pnpm", "store"),
+		)
+	default:
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		cacheHome := os.Getenv("XDG_CACHE_HOME")
+		if cacheHome == "" {
+			cacheHome = filepath.Join(home, ".cache")
+		}
+		folders = append(folders,
+			filepath.Join(cacheHome, "yarn"),
+			filepath.Join(dataHome, "pnpm", "store"),
+		)
+	}
+
+	return folders, nil
+}
+
+// runCaches is run for the global package manager caches. A cache's age is
+// the age of the newest file within it, and the same size and age limits
+// apply as for project folders.
+func runCaches(ctx context.Context, o *Options) (*Result, error) {
+	folders, err := cacheFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	results := newResults()
+	defer o.countEvents(results)()
+	fsys := o.files()
+	var stopErr error
+	for _, path := range folders {
+		if stopErr = ctx.Err(); stopErr != nil {
+			break
+		}
+
+		info, err := fsys.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			results.addError(o, &Folder{Path: path}, err)
+			continue
+		}
+		if !info.IsDir() {
+			continue
+		}
+		results.sawTarget()
+
+		folder := &Folder{Path: path}
+		modTime, err := latestModifiedFile(fsys, path, nil)
+		if err != nil {
+			results.addError(o, folder, err)
+			continue
+		}
+		folder.ModTime = modTime
+		folder.ModDaysAgo = daysSince(modTime)
+
+		if time.Since(modTime) < o.OlderThan {
+			o.skipped(folder, SkipTooRecent)
+			continue
+		}
+		o.emit(Event{Kind: FolderFound, Folder: folder})
+
+		u, err := folderSize(ctx, fsys, path, o.DiskUsage)
+		if err != nil && ctx.Err() != nil {
+			stopErr = err
+			break
+		}
+		if err != nil {
+			results.addError(o, folder, err)
+			continue
+		}
+
+		folder.setUsage(u)
+		o.emit(Event{Kind: SizeComputed, Folder: folder})
+
+		if u.SizeBytes < o.MinSize {
+			o.skipped(folder, SkipTooSmall)
+			continue
+		}
+
+		if o.MaxSize > 0 && u.SizeBytes > o.MaxSize {
+			o.skipped(folder, SkipNeedReview)
+			results.addReview(folder)
+			continue
+		}
+
+		results.add(folder)
+	}
+
+	results.sort(o.SortBy, o.Reverse)
+	results.truncate(o.Limit)
+	return results, stopErr
+}
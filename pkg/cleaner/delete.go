@@ -0,0 +1,343 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DeleteLargestFirst  = "largest"
+	DeleteSmallestFirst = "smallest"
+	DeleteOldestFirst   = "oldest"
+)
+
+func ValidDeleteOrder(order string) bool {
+	switch order {
+	case DeleteLargestFirst, DeleteSmallestFirst, DeleteOldestFirst:
+		return true
+	}
+	return false
+}
+
+var errTrashNotReal = errors.New("the trash can only be used on the real filesystem")
+
+// DefaultDeleteWorkers is how many folders are deleted at once by default.
+// Removing a folder of many small files spends most of its time waiting on
+// the filesystem, so a few at once is much faster than one at a time.
+const DefaultDeleteWorkers = 4
+
+// DeleteResult is the outcome of attempting to delete a single folder.
+type DeleteResult struct {
+	Path       string
+	Deleted    bool
+	BytesFreed int64
+	Trashed    bool
+	// Archive is where the folder was archived to, if Options.ArchiveDir is
+	// set.
+	Archive string
+	Err     error
+}
+
+// Delete removes each folder in results, starting them in the order given by
+// opts.DeleteOrder and removing up to opts.DeleteWorkers at once. A folder
+// that fails to delete is reported in its result and the rest are still
+// tried. As each folder finishes onResult is called with its outcome, from
+// one goroutine at a time; returning false stops any more being started.
+// Once ctx is cancelled no more folders are started, though those already
+// being removed are finished. It never prints or exits; presentation is left
+// to the caller.
+//
+// On the real filesystem every folder is first renamed to a hidden name next
+// to it, so all the projects are clean at once, and then removed. Folders
+// that end up not being removed are renamed back. If the process dies first
+// they are left for PendingDeletions, and how far it got for InterruptedRuns.
+func Delete(ctx context.Context, results *Result, opts Options, onResult func(DeleteResult) bool) []DeleteResult {
+	o := &opts
+	fsys := o.files()
+	folders := deleteOrder(results.Folders, o.DeleteOrder)
+
+	var pending *pendingList
+	var j *journal
+	if o.real() {
+		j = startJournal(folders)
+		defer j.finish()
+		if !o.Trash {
+			pending = loadPending()
+		}
+	}
+	paths := make(map[*Folder]string, len(folders))
+	for _, f := range folders {
+		paths[f] = f.Path
+		if ctx.Err() == nil && (f.Project == "" || !isKept(fsys, f.Project)) && checkDeletable(fsys, o, f.Path, f.Path) == nil {
+			paths[f] = pending.release(f.Path)
+		}
+	}
+
+	workers := o.DeleteWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	type outcome struct {
+		f *Folder
+		r DeleteResult
+	}
+	jobs := make(chan *Folder)
+	done := make(chan outcome)
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				j.set(f.Path, JournalRemoving)
+				r := deleteFolder(fsys, o, f, paths[f], pending)
+				if r.Err != nil {
+					j.set(f.Path, JournalFailed)
+				} else {
+					j.set(f.Path, JournalRemoved)
+				}
+				done <- outcome{f, r}
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for _, f := range folders {
+			if ctx.Err() != nil {
+				break
+			}
+			select {
+			case jobs <- f:
+			case <-ctx.Done():
+				break feed
+			case <-stop:
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	out := make([]DeleteResult, 0, len(folders))
+	stopped := false
+	for d := range done {
+		if d.r.Err != nil {
+			o.emit(Event{Kind: Error, Folder: d.f, Err: d.r.Err})
+		} else {
+			o.emit(Event{Kind: Deleted, Folder: d.f})
+		}
+
+		out = append(out, d.r)
+		if !stopped && onResult != nil && !onResult(d.r) {
+			stopped = true
+			close(stop)
+		}
+	}
+
+	started := make(map[string]bool, len(out))
+	for _, r := range out {
+		started[r.Path] = true
+	}
+	for _, f := range folders {
+		if paths[f] != f.Path && !started[f.Path] {
+			_ = pending.restore(PendingDeletion{Path: paths[f], Original: f.Path})
+		}
+	}
+
+	return out
+}
+
+// deleteFolder checks f is still safe to delete, archives it if asked, then
+// removes or trashes it, and returns the outcome. f has been released to path
+// if that is different, and is renamed back if it can't be checked, archived
+// or removed.
+func deleteFolder(fsys fileSystem, o *Options, f *Folder, path string, pending *pendingList) DeleteResult {
+	r := DeleteResult{Path: f.Path}
+	if f.Project != "" && isKept(fsys, f.Project) {
+		r.Err = errKept
+	} else if r.Err = checkDeletable(fsys, o, f.Path, path); r.Err != nil {
+		if path != f.Path {
+			_ = pending.restore(PendingDeletion{Path: path, Original: f.Path})
+		}
+	} else if o.ArchiveDir != "" && !o.real() {
+		r.Err = errArchiveNotReal
+	} else if r.Archive, r.Err = archiveIfSet(o, path, f.Path); r.Err != nil {
+		if path != f.Path {
+			_ = pending.restore(PendingDeletion{Path: path, Original: f.Path})
+		}
+	} else if o.Trash && !o.real() {
+		r.Err = errTrashNotReal
+	} else if o.Trash {
+		if err := removeAndVerify(fsys, f.Path, moveToTrash, o); err != nil {
+			r.Err = err
+		} else if err := deleteMembers(fsys, o, f, pending); err != nil {
+			r.Err = err
+		} else {
+			r.Trashed = true
+		}
+	} else if err := removeReleased(fsys, o, f.Path, path, pending); err != nil {
+		r.Err = err
+	} else if err := deleteMembers(fsys, o, f, pending); err != nil {
+		r.Err = err
+	} else {
+		r.Deleted = true
+		r.BytesFreed = f.ReclaimableBytes
+	}
+	return r
+}
+
+// deleteMembers deletes the folders of the workspace packages in f, in the
+// same way as f, stopping at the first that fails.
+func deleteMembers(fsys fileSystem, o *Options, f *Folder, pending *pendingList) error {
+	for _, m := range f.Members {
+		if err := checkDeletable(fsys, o, m, m); err != nil {
+			return err
+		}
+		if _, err := archiveIfSet(o, m, m); err != nil {
+			return err
+		}
+		var err error
+		if o.Trash {
+			err = removeAndVerify(fsys, m, moveToTrash, o)
+		} else {
+			err = removeReleased(fsys, o, m, pending.release(m), pending)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveIfSet archives the folder at path, released from original, if
+// o.ArchiveDir is set.
+func archiveIfSet(o *Options, path, original string) (string, error) {
+	if o.ArchiveDir == "" {
+		return "", nil
+	}
+	return archiveFolder(o, path, original)
+}
+
+// removeReleased removes the folder at path, released from original. It
+// fails if nothing is left at path, rather than report a folder that was put
+// back as deleted.
+func removeReleased(fsys fileSystem, o *Options, original, path string, pending *pendingList) error {
+	if path == original {
+		return removeAndVerify(fsys, path, fsys.RemoveAll, o)
+	}
+
+	e := PendingDeletion{Path: path, Original: original}
+	if err := pending.startRemoving(e); err != nil {
+		return err
+	}
+	if err := removeAndVerify(fsys, path, fsys.RemoveAll, o); err != nil {
+		_ = pending.restore(e)
+		return err
+	}
+	pending.remove(path)
+	return nil
+}
+
+// deleteOrder returns a copy of folders sorted for deletion, leaving the
+// display order untouched.
+func deleteOrder(folders []*Folder, order string) []*Folder {
+	sorted := make([]*Folder, len(folders))
+	copy(sorted, folders)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		switch order {
+		case DeleteSmallestFirst:
+			return sorted[i].SizeBytes < sorted[j].SizeBytes
+		case DeleteOldestFirst:
+			return sorted[i].ModDaysAgo > sorted[j].ModDaysAgo
+		default:
+			return sorted[i].SizeBytes > sorted[j].SizeBytes
+		}
+	})
+
+	return sorted
+}
+
+// removeAndVerify removes p with remove and then checks it is really gone, as
+// some network filesystems report success while leaving files behind. If
+// anything remains the removal is retried up to o.VerifyRetries more times.
+func removeAndVerify(fsys fileSystem, p string, remove func(string) error, o *Options) error {
+	var err error
+	for attempt := 0; attempt <= o.VerifyRetries; attempt++ {
+		if err = removeUnlocked(p, remove, o.LockRetries); err != nil {
+			continue
+		}
+
+		if err = verifyRemoved(fsys, p); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// lockRetryDelay is how long to wait before the first retry of a locked
+// folder. The wait doubles for each retry after that.
+const lockRetryDelay = 250 * time.Millisecond
+
+// removeUnlocked removes p with remove, retrying up to retries times with a
+// growing delay while a file in it is locked by another process.
+func removeUnlocked(p string, remove func(string) error, retries int) error {
+	delay := lockRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := remove(p)
+		if err == nil || !isLocked(err) {
+			return err
+		}
+		if attempt == retries {
+			if holders := lockHolders(p, err); len(holders) > 0 {
+				return fmt.Errorf("still in use by %s after %d retries: %w", strings.Join(holders, ", "), retries, err)
+			}
+			return fmt.Errorf("still in use by another program after %d retries: %w", retries, err)
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func describeProcess(name string, pid int) string {
+	return fmt.Sprintf("%s (pid %d)", name, pid)
+}
+
+// isWithin reports whether path is dir or anything below it.
+func isWithin(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func verifyRemoved(fsys fileSystem, p string) error {
+	_, err := fsys.Lstat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	remaining := 0
+	_ = walkDir(fsys, p, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			remaining++
+		}
+		return nil
+	})
+
+	return fmt.Errorf("still exists after delete with %d files remaining", remaining)
+}
@@ -0,0 +1,191 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestMain keeps the journal and pending list the tests write out of the
+// real user cache folder.
+func TestMain(m *testing.M) {
+	cache, err := os.MkdirTemp("", "npm-cleaner-cache")
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range []string{"XDG_CACHE_HOME", "HOME", "LocalAppData"} {
+		_ = os.Setenv(name, cache)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(cache)
+	os.Exit(code)
+}
+
+// writeFiles creates each file in files below dir, with its folders.
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	errRemove := errors.New("remove failed")
+
+	tests := []struct {
+		name  string
+		files []string
+		// setup, if set, changes the options or the folders before
+		// deleting.
+		setup   func(t *testing.T, root string, o *Options)
+		folder  string
+		remover Remover
+		wantErr error
+		gone    bool
+	}{
+		{
+			name:   "removes node_modules",
+			files:  []string{"app/package.json", "app/node_modules/left-pad/index.js"},
+			folder: "app/node_modules",
+			gone:   true,
+		},
+		{
+			name:    "refuses a folder that isn't a target",
+			files:   []string{"app/package.json", "app/src/index.js"},
+			folder:  "app/src",
+			wantErr: errUnsafe,
+		},
+		{
+			name:   "refuses a folder outside the start folder",
+			files:  []string{"app/package.json", "app/node_modules/left-pad/index.js"},
+			folder: "app/node_modules",
+			setup: func(t *testing.T, root string, o *Options) {
+				o.FromDir = t.TempDir()
+			},
+			wantErr: errUnsafe,
+		},
+		{
+			name:    "keeps a project marked to be kept",
+			files:   []string{"app/package.json", "app/" + KeepMarker, "app/node_modules/left-pad/index.js"},
+			folder:  "app/node_modules",
+			wantErr: errKept,
+		},
+		{
+			name:    "puts back a folder that can't be removed",
+			files:   []string{"app/package.json", "app/node_modules/left-pad/index.js"},
+			folder:  "app/node_modules",
+			remover: RemoverFunc(func(string) error { return errRemove }),
+			wantErr: errRemove,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files...)
+
+			o := DefaultOptions()
+			o.FromDir = root
+			o.Remover = tt.remover
+			o.LockRetries = 0
+			if tt.setup != nil {
+				tt.setup(t, root, &o)
+			}
+
+			p := filepath.Join(root, filepath.FromSlash(tt.folder))
+			f := &Folder{Path: p, Project: filepath.Dir(p), SizeBytes: 1, ReclaimableBytes: 1}
+			out := Delete(context.Background(), &Result{Folders: []*Folder{f}}, o, nil)
+			if len(out) != 1 {
+				t.Fatalf("got %d results, want 1", len(out))
+			}
+
+			r := out[0]
+			if !errors.Is(r.Err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", r.Err, tt.wantErr)
+			}
+			if r.Deleted != (tt.wantErr == nil) {
+				t.Errorf("deleted = %t, want %t", r.Deleted, tt.wantErr == nil)
+			}
+			if r.Deleted && r.BytesFreed != f.ReclaimableBytes {
+				t.Errorf("bytes freed = %d, want %d", r.BytesFreed, f.ReclaimableBytes)
+			}
+
+			_, err := os.Lstat(p)
+			if gone := errors.Is(err, fs.ErrNotExist); gone != tt.gone {
+				t.Errorf("folder gone = %t, want %t", gone, tt.gone)
+			}
+			entries, err := os.ReadDir(filepath.Dir(p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if isPending(e.Name()) {
+					t.Errorf("%s left renamed for removal", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteRefusesLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating links needs extra privileges on Windows")
+	}
+
+	root := t.TempDir()
+	writeFiles(t, root, "app/package.json", "elsewhere/left-pad/index.js")
+	p := filepath.Join(root, "app", NodeModules)
+	if err := os.Symlink(filepath.Join(root, "elsewhere"), p); err != nil {
+		t.Fatal(err)
+	}
+
+	o := DefaultOptions()
+	o.FromDir = root
+	f := &Folder{Path: p, Project: filepath.Dir(p)}
+	out := Delete(context.Background(), &Result{Folders: []*Folder{f}}, o, nil)
+	if len(out) != 1 || !errors.Is(out[0].Err, errUnsafe) {
+		t.Fatalf("got %+v, want it refused as unsafe", out)
+	}
+	if _, err := os.Stat(filepath.Join(root, "elsewhere", "left-pad", "index.js")); err != nil {
+		t.Errorf("link target changed: %v", err)
+	}
+}
+
+func TestDeleteOrder(t *testing.T) {
+	small := &Folder{Path: "small", SizeBytes: 1, ModDaysAgo: 30}
+	large := &Folder{Path: "large", SizeBytes: 100, ModDaysAgo: 10}
+	old := &Folder{Path: "old", SizeBytes: 10, ModDaysAgo: 90}
+	folders := []*Folder{small, large, old}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{DeleteLargestFirst, []string{"large", "old", "small"}},
+		{DeleteSmallestFirst, []string{"small", "old", "large"}},
+		{DeleteOldestFirst, []string{"old", "small", "large"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			sorted := deleteOrder(folders, tt.order)
+			for i, f := range sorted {
+				if f.Path != tt.want[i] {
+					t.Fatalf("order %s: got %s at %d, want %s", tt.order, f.Path, i, tt.want[i])
+				}
+			}
+			if folders[0] != small {
+				t.Errorf("folders were reordered in place")
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// device returns the filesystem the file described by info is on.
+func device(info fs.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package cleaner
+
+// fixedDrives returns no drives, as other systems have a single root.
+func fixedDrives() []string {
+	return nil
+}
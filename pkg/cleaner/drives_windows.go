@@ -0,0 +1,36 @@
+package cleaner
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const driveFixed = 3
+
+var (
+	procGetLogicalDrives = syscall.NewLazyDLL("kernel32.dll").NewProc("GetLogicalDrives")
+	procGetDriveTypeW    = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDriveTypeW")
+)
+
+// fixedDrives returns the root of every fixed drive, leaving out removable,
+// network and optical drives.
+func fixedDrives() []string {
+	mask, _, _ := procGetLogicalDrives.Call()
+
+	var drives []string
+	for i := 0; i < 26; i++ {
+		if mask&(1<<uint(i)) == 0 {
+			continue
+		}
+
+		root := string(rune('A'+i)) + `:\`
+		name, err := syscall.UTF16PtrFromString(root)
+		if err != nil {
+			continue
+		}
+		if kind, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(name))); kind == driveFixed {
+			drives = append(drives, root)
+		}
+	}
+	return drives
+}
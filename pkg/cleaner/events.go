@@ -0,0 +1,95 @@
+package cleaner
+
+import "strconv"
+
+// EventKind says what an Event is reporting.
+type EventKind int
+
+const (
+	// FolderFound is a target folder that is old enough, and is going to be
+	// sized.
+	FolderFound EventKind = iota
+	// FolderSkipped is a target folder left out of the results, or set aside
+	// for review, with the reason in Event.Reason.
+	FolderSkipped
+	// SizeComputed is a found folder whose size is now known.
+	SizeComputed
+	// Deleted is a folder that has been deleted or moved to the trash.
+	Deleted
+	// Error is a problem with a folder, in Event.Err.
+	Error
+	// FolderVisited is a folder the scan walked into or, with Event.Reason
+	// set, one it didn't look inside. There is one for every folder scanned,
+	// for tracing what a scan did.
+	FolderVisited
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case FolderFound:
+		return "FolderFound"
+	case FolderSkipped:
+		return "FolderSkipped"
+	case SizeComputed:
+		return "SizeComputed"
+	case Deleted:
+		return "Deleted"
+	case Error:
+		return "Error"
+	case FolderVisited:
+		return "FolderVisited"
+	}
+	return "EventKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Reasons a folder is skipped, given in Event.Reason.
+const (
+	SkipKept       = "kept"
+	SkipNotOrphan  = "not an orphan"
+	SkipTooRecent  = "modified too recently"
+	SkipTooSmall   = "smaller than the minimum size"
+	SkipUnsaved    = "uncommitted or unpushed changes"
+	SkipNeedReview = "larger than the maximum size"
+	SkipWorkspace  = "workspace root not included"
+	SkipKeepRecent = "among the most recently modified kept"
+	SkipFreeGoal   = "not needed to meet the free space goal"
+	SkipOverLimit  = "over the limit on folders listed"
+	SkipTracked    = "build output tracked by git"
+	SkipNoCaches   = "no caches inside"
+
+	SkipPendingDelete = "being deleted"
+	SkipHiddenFolder  = "hidden"
+	SkipSystemFolder  = "system folder"
+	SkipExcluded      = "excluded"
+	SkipOtherFS       = "on another filesystem"
+	SkipRemote        = "network share or cloud storage"
+	SkipTooDeep       = "deeper than the maximum depth"
+)
+
+// Event reports progress as a scan or deletion happens, so a frontend can
+// show folders as they are found rather than waiting for the Result.
+type Event struct {
+	Kind   EventKind
+	Folder *Folder
+	Reason string
+	Err    error
+}
+
+// emit passes e to OnEvent, if set.
+func (o *Options) emit(e Event) {
+	if o.OnEvent != nil {
+		o.OnEvent(e)
+	}
+}
+
+func (o *Options) skipped(f *Folder, reason string) {
+	o.emit(Event{Kind: FolderSkipped, Folder: f, Reason: reason})
+}
+
+// visited reports a folder walked into, or skipped for reason, only making
+// the Folder if OnEvent is set as it is called for every folder.
+func (o *Options) visited(path, reason string) {
+	if o.OnEvent != nil {
+		o.OnEvent(Event{Kind: FolderVisited, Folder: &Folder{Path: path}, Reason: reason})
+	}
+}
@@ -0,0 +1,61 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CompileExclude turns an -exclude pattern into a regular expression matched
+// against slash separated paths. A leading ~ is the home folder. Within a
+// pattern * matches within a single folder name, ** matches across folders and
+// ? matches one character. A pattern with no wildcards is a path prefix.
+// Relative patterns may match at any depth.
+func CompileExclude(pattern string) (*regexp.Regexp, error) {
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") || strings.HasPrefix(pattern, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		pattern = home + pattern[1:]
+	}
+
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+
+	var b strings.Builder
+	if !filepath.IsAbs(filepath.FromSlash(pattern)) && !strings.HasPrefix(pattern, "/") {
+		b.WriteString("(^|.*/)")
+	} else {
+		b.WriteString("^")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; {
+		case ch == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case ch == '*':
+			b.WriteString("[^/]*")
+		case ch == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	// Anything below a matching folder is excluded too.
+	b.WriteString("(/.*)?$")
+
+	return regexp.Compile(b.String())
+}
+
+func isExcluded(excludes []*regexp.Regexp, path string) bool {
+	slashed := filepath.ToSlash(path)
+	for _, re := range excludes {
+		if re.MatchString(slashed) {
+			return true
+		}
+	}
+	return false
+}
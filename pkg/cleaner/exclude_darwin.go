@@ -0,0 +1,22 @@
+package cleaner
+
+import "regexp"
+
+var excludeFolders = []*regexp.Regexp{
+	matchRootFolders("System"),
+}
+
+// libraryFolders are skipped unless Options.IncludeLibrary is set. Nothing in
+// them is safe to delete, and Library is slow to walk: application support
+// files, app containers and caches live there. Time Machine snapshots and
+// backups are read only copies, and the node_modules inside an app bundle are
+// part of the app.
+var libraryFolders = []*regexp.Regexp{
+	matchFolders("Library"),
+	matchRootFolders(`private/var/folders`),
+	matchRootFolders(`\.MobileBackups`),
+	matchRootFolders(`Volumes/com\.apple\.TimeMachine\.localsnapshots`),
+	matchRootFolders(`Volumes/\.timemachine`),
+	matchFolders(`Backups\.backupdb`),
+	matchFolders(`[^/]+\.app`),
+}
@@ -0,0 +1,23 @@
+//go:build !windows && !darwin
+
+package cleaner
+
+import "regexp"
+
+var excludeFolders = []*regexp.Regexp{
+	matchRootFolders("proc"),
+	matchRootFolders("sys"),
+	matchRootFolders("dev"),
+	matchRootFolders("run"),
+	matchRootFolders(`lost\+found`),
+	// Snap and flatpak packages are read only images, and the node_modules
+	// in container images belong to them.
+	matchRootFolders("snap"),
+	matchRootFolders("var/lib/snapd"),
+	matchRootFolders("var/lib/flatpak"),
+	matchRootFolders("var/lib/docker"),
+	matchRootFolders("var/lib/containers"),
+}
+
+// libraryFolders are only skipped on macOS.
+var libraryFolders []*regexp.Regexp
@@ -0,0 +1,14 @@
+package cleaner
+
+import "regexp"
+
+var excludeFolders = []*regexp.Regexp{
+	matchFolders("AppData"),
+	matchFolders("Program Files"),
+	matchFolders(regexp.QuoteMeta("Program Files (x86)")),
+	matchFolders("Windows"),
+	matchFolders(regexp.QuoteMeta("$Recycle.Bin")),
+}
+
+// libraryFolders are only skipped on macOS.
+var libraryFolders []*regexp.Regexp
@@ -0,0 +1,53 @@
+package cleaner
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+)
+
+var (
+	procLockFileEx   = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+	procUnlockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("UnlockFileEx")
+)
+
+// TryLock takes an exclusive lock on f without waiting, reporting false if
+// another process holds it. The byte locked is far past the end of the file,
+// as locked bytes can't be read by anyone else on Windows.
+func TryLock(f *os.File) (bool, error) {
+	ol := syscall.Overlapped{OffsetHigh: 1}
+	ok, _, callErr := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if ok != 0 {
+		return true, nil
+	}
+	if callErr == errorLockViolation {
+		return false, nil
+	}
+	return false, callErr
+}
+
+// Lock takes an exclusive lock on f, waiting for any other process holding
+// it to let go.
+func Lock(f *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: 1}
+	ok, _, callErr := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if ok == 0 {
+		return callErr
+	}
+	return nil
+}
+
+// Unlock releases a lock taken on f by Lock or TryLock.
+func Unlock(f *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: 1}
+	ok, _, callErr := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if ok == 0 {
+		return callErr
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !windows && !linux && !darwin && !freebsd
+
+package cleaner
+
+import "errors"
+
+func freeSpace(path string) (int64, error) {
+	return 0, errors.New("free space isn't available on this platform")
+}
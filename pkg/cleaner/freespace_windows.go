@@ -0,0 +1,24 @@
+package cleaner
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// freeSpace returns the space available to the user on the volume holding
+// path.
+func freeSpace(path string) (int64, error) {
+	name, err := syscall.UTF16PtrFromString(longPath(path))
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+	ok, _, callErr := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if ok == 0 {
+		return 0, callErr
+	}
+	return int64(available), nil
+}
@@ -0,0 +1,181 @@
+package cleaner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Remover deletes a folder and everything in it, like os.RemoveAll.
+type Remover interface {
+	RemoveAll(path string) error
+}
+
+// RemoverFunc adapts a function such as os.RemoveAll to a Remover.
+type RemoverFunc func(path string) error
+
+func (f RemoverFunc) RemoveAll(path string) error {
+	return f(path)
+}
+
+var errNoRemover = errors.New("no Remover set to delete from Options.FS")
+
+// fileSystem is what scanning and deleting read and change. Paths are in the
+// operating system's form, as shown to the user.
+type fileSystem interface {
+	Lstat(name string) (fs.FileInfo, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+	RemoveAll(name string) error
+}
+
+// files returns the filesystem to scan: o.FS if set, otherwise the real one.
+func (o *Options) files() fileSystem {
+	if o.FS != nil {
+		return virtualFS{fsys: o.FS, remover: o.Remover}
+	}
+	return osFS{remover: o.Remover}
+}
+
+// real reports whether the scan is of the real filesystem, which is needed
+// for anything that runs git or keeps state between runs.
+func (o *Options) real() bool {
+	return o.FS == nil
+}
+
+// osFS is the real filesystem. Paths are passed through longPath so folders
+// deep inside node_modules can be read and deleted on Windows.
+type osFS struct {
+	remover Remover
+}
+
+func (osFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(longPath(name)) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(longPath(name)) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(longPath(name)) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(longPath(name)) }
+
+func (f osFS) RemoveAll(name string) error {
+	if f.remover != nil {
+		return f.remover.RemoveAll(name)
+	}
+	return removeAll(longPath(name))
+}
+
+// virtualFS reads an fs.FS such as an fstest.MapFS or a recorded snapshot.
+// Paths are slash separated from the root of fsys, with or without a leading
+// slash, so a FromDir of "/" scans all of it.
+type virtualFS struct {
+	fsys    fs.FS
+	remover Remover
+}
+
+func (v virtualFS) name(p string) string {
+	p = strings.Trim(filepath.ToSlash(p), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// Lstat is the same as Stat, as fs.FS has no symbolic links.
+func (v virtualFS) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Stat(v.fsys, v.name(name))
+}
+
+func (v virtualFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(v.fsys, v.name(name))
+}
+
+func (v virtualFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(v.fsys, v.name(name))
+}
+
+func (v virtualFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(v.fsys, v.name(name))
+}
+
+func (v virtualFS) RemoveAll(name string) error {
+	if v.remover == nil {
+		return errNoRemover
+	}
+	return v.remover.RemoveAll(name)
+}
+
+// isLink reports whether d is a symbolic link, or on Windows a junction or
+// other reparse point. Links are never walked into, as their targets are
+// outside the folder being sized or deleted, such as a workspace package
+// linked into node_modules.
+func isLink(d fs.DirEntry) bool {
+	return d.Type()&(fs.ModeSymlink|fs.ModeIrregular) != 0
+}
+
+// walkDir is filepath.WalkDir over fsys, except that it doesn't walk into
+// links even where they are reported as folders.
+func walkDir(fsys fileSystem, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirEntry(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walkDirEntry(fsys fileSystem, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() || isLink(d) {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		if err = fn(path, d, err); err != nil {
+			if err == fs.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		if err := walkDirEntry(fsys, filepath.Join(path, e.Name()), e, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// glob is filepath.Glob over fsys, for patterns with wildcards only in the
+// last element.
+func glob(fsys fileSystem, pattern string) ([]string, error) {
+	dir, base := filepath.Split(pattern)
+	dir = filepath.Clean(dir)
+	if _, err := filepath.Match(base, ""); err != nil {
+		return nil, err
+	}
+
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, nil
+	}
+
+	var matches []string
+	for _, e := range entries {
+		if ok, _ := filepath.Match(base, e.Name()); ok {
+			matches = append(matches, filepath.Join(dir, e.Name()))
+		}
+	}
+	return matches, nil
+}
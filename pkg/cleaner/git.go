@@ -0,0 +1,113 @@
+package cleaner
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitRoot returns the top of the git repo holding project, which is project
+// itself or a folder above it, as for a package in a monorepo. If git can't
+// be run, the nearest folder with a .git folder, or .git file for worktrees
+// and submodules, is taken to be the top instead.
+func gitRoot(project string) (string, bool) {
+	out, err := exec.Command("git", "-C", project, "rev-parse", "--show-toplevel").Output()
+	if err == nil {
+		return filepath.FromSlash(strings.TrimSpace(string(out))), true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", false
+	}
+
+	for dir := project; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// isGitRepo reports whether project is in a git repo, at its top or in a
+// folder below it.
+func isGitRepo(project string) bool {
+	_, ok := gitRoot(project)
+	return ok
+}
+
+// lastCommitTime returns the time of the latest commit changing anything in
+// project, which for a package in a monorepo can be well before the latest
+// commit in the repo. If git can't be run, or nothing in project has been
+// committed, the modified time of .git/logs/HEAD, then .git/HEAD, at the top
+// of the repo is used instead as they change on every commit and checkout.
+func lastCommitTime(project string) (time.Time, error) {
+	out, err := exec.Command("git", "-C", project, "log", "-1", "--format=%ct", "--", ".").Output()
+	if err == nil {
+		if secs, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64); err == nil {
+			return time.Unix(secs, 0), nil
+		}
+	}
+
+	root, ok := gitRoot(project)
+	if !ok {
+		root = project
+	}
+	var statErr error
+	for _, p := range []string{filepath.Join(".git", "logs", "HEAD"), filepath.Join(".git", "HEAD")} {
+		info, err := os.Stat(filepath.Join(root, p))
+		if err == nil {
+			return info.ModTime(), nil
+		}
+		statErr = err
+	}
+
+	return time.Time{}, statErr
+}
+
+// isTracked reports whether git tracks any file in the folder at path, in
+// the repo holding project, so deleting it would lose work rather than build
+// output. A project that isn't in a repo has nothing tracked.
+func isTracked(project, path string) bool {
+	rel, err := filepath.Rel(project, path)
+	if err != nil {
+		return false
+	}
+	out, err := exec.Command("git", "-C", project, "ls-files", "--", filepath.ToSlash(rel)).Output()
+	return err == nil && len(strings.TrimSpace(string(out))) > 0
+}
+
+// hasUnsavedWork reports whether project, in a git repo, has uncommitted
+// changes, including untracked files, or commits changing it that haven't
+// been pushed to any remote. A repo without a remote has nowhere to push to,
+// so only its uncommitted changes count. Changes within the found folder at
+// path are ignored, in case it is not in .gitignore. If git can't tell us,
+// the project is assumed to have unsaved work.
+func hasUnsavedWork(project, path string) bool {
+	rel, err := filepath.Rel(project, path)
+	if err != nil {
+		return true
+	}
+
+	status, err := exec.Command("git", "-C", project, "status", "--porcelain", "--",
+		".", ":(exclude)"+filepath.ToSlash(rel)).Output()
+	if err != nil || len(strings.TrimSpace(string(status))) > 0 {
+		return true
+	}
+
+	remotes, err := exec.Command("git", "-C", project, "remote").Output()
+	if err != nil {
+		return true
+	}
+	if len(strings.TrimSpace(string(remotes))) == 0 {
+		return false
+	}
+
+	unpushed, err := exec.Command("git", "-C", project, "log", "--branches", "--not", "--remotes", "--format=%H", "-1", "--", ".").Output()
+	return err != nil || len(strings.TrimSpace(string(unpushed))) > 0
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// hardLinks returns the identity of the file described by info and how many
+// names it has, if the platform reports it.
+func hardLinks(info fs.FileInfo) (fileID, uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, uint64(st.Nlink), true
+}
@@ -0,0 +1,39 @@
+package cleaner
+
+import (
+	"math"
+)
+
+// Bucket counts the folders whose size falls within [MinSize, MaxSize).
+type Bucket struct {
+	Label     string
+	MinSize   int64
+	MaxSize   int64
+	Count     int
+	TotalSize int64
+}
+
+func newBuckets() []*Bucket {
+	return []*Bucket{
+		{Label: "0-50MB", MinSize: 0, MaxSize: 50 * MB},
+		{Label: "50-250MB", MinSize: 50 * MB, MaxSize: 250 * MB},
+		{Label: "250MB-1GB", MinSize: 250 * MB, MaxSize: GB},
+		{Label: ">1GB", MinSize: GB, MaxSize: math.MaxInt64},
+	}
+}
+
+// Histogram buckets every discovered folder by size.
+func (r *Result) Histogram() []*Bucket {
+	buckets := newBuckets()
+	for _, f := range r.Discovered {
+		for _, b := range buckets {
+			if f.SizeBytes >= b.MinSize && f.SizeBytes < b.MaxSize {
+				b.Count++
+				b.TotalSize += f.SizeBytes
+				break
+			}
+		}
+	}
+
+	return buckets
+}
@@ -0,0 +1,30 @@
+package cleaner
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// lockHolders describes the processes with a file open under p, using lsof
+// as macOS has no /proc.
+func lockHolders(p string, err error) []string {
+	// -F pc prints a "p<pid>" line then a "c<command>" line for each process.
+	out, _ := exec.Command("lsof", "-F", "pc", "+D", p).Output()
+
+	var holders []string
+	pid := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "p"):
+			pid, _ = strconv.Atoi(line[1:])
+		case strings.HasPrefix(line, "c") && pid != 0:
+			holders = append(holders, describeProcess(line[1:], pid))
+		}
+	}
+	return holders
+}
@@ -0,0 +1,60 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lockHolders describes the processes with a file open, or their working
+// folder, under p, by looking through every process's open files in /proc.
+// Processes belonging to other users can't be seen without root.
+func lockHolders(p string, err error) []string {
+	procs, readErr := os.ReadDir("/proc")
+	if readErr != nil {
+		return nil
+	}
+
+	var holders []string
+	for _, proc := range procs {
+		pid, convErr := strconv.Atoi(proc.Name())
+		if convErr != nil || pid == os.Getpid() {
+			continue
+		}
+
+		dir := filepath.Join("/proc", proc.Name())
+		if holdsPath(dir, p) {
+			holders = append(holders, describeProcess(processName(dir), pid))
+		}
+	}
+	return holders
+}
+
+// holdsPath reports whether the process whose /proc folder is dir has a file
+// open or its working folder under p.
+func holdsPath(dir, p string) bool {
+	if cwd, err := os.Readlink(filepath.Join(dir, "cwd")); err == nil && isWithin(p, cwd) {
+		return true
+	}
+
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return false
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+		if err == nil && isWithin(p, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func processName(dir string) string {
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(comm))
+}
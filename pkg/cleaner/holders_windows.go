@@ -0,0 +1,89 @@
+package cleaner
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	errorMoreData = 234
+
+	// Sizes from RestartManager.h, including the terminating NUL.
+	rmSessionKeyLen = 33
+	rmMaxAppName    = 256
+	rmMaxSvcName    = 64
+)
+
+// rmProcessInfo mirrors RM_PROCESS_INFO.
+type rmProcessInfo struct {
+	processID        uint32
+	processStartTime syscall.Filetime
+	appName          [rmMaxAppName]uint16
+	serviceShortName [rmMaxSvcName]uint16
+	applicationType  uint32
+	appStatus        uint32
+	tsSessionID      uint32
+	restartable      int32
+}
+
+var (
+	rstrtmgr                = syscall.NewLazyDLL("rstrtmgr.dll")
+	procRmStartSession      = rstrtmgr.NewProc("RmStartSession")
+	procRmRegisterResources = rstrtmgr.NewProc("RmRegisterResources")
+	procRmGetList           = rstrtmgr.NewProc("RmGetList")
+	procRmEndSession        = rstrtmgr.NewProc("RmEndSession")
+)
+
+// lockHolders describes the processes holding open the file that err failed
+// on, or p if err doesn't name one, using the Restart Manager.
+func lockHolders(p string, err error) []string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		p = pathErr.Path
+	}
+	if procRmStartSession.Find() != nil {
+		return nil
+	}
+
+	var session uint32
+	var key [rmSessionKeyLen]uint16
+	if r, _, _ := procRmStartSession.Call(uintptr(unsafe.Pointer(&session)), 0, uintptr(unsafe.Pointer(&key[0]))); r != 0 {
+		return nil
+	}
+	defer procRmEndSession.Call(uintptr(session))
+
+	name, convErr := syscall.UTF16PtrFromString(p)
+	if convErr != nil {
+		return nil
+	}
+	if r, _, _ := procRmRegisterResources.Call(uintptr(session), 1, uintptr(unsafe.Pointer(&name)), 0, 0, 0, 0); r != 0 {
+		return nil
+	}
+
+	var needed, count, reasons uint32
+	var infos []rmProcessInfo
+	for {
+		var first *rmProcessInfo
+		if len(infos) > 0 {
+			first = &infos[0]
+		}
+		count = uint32(len(infos))
+		r, _, _ := procRmGetList.Call(uintptr(session), uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(first)), uintptr(unsafe.Pointer(&reasons)))
+		if r == errorMoreData {
+			infos = make([]rmProcessInfo, needed)
+			continue
+		}
+		if r != 0 {
+			return nil
+		}
+		break
+	}
+
+	holders := make([]string, 0, count)
+	for _, info := range infos[:count] {
+		holders = append(holders, describeProcess(syscall.UTF16ToString(info.appName[:]), int(info.processID)))
+	}
+	return holders
+}
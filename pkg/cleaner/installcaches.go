@@ -0,0 +1,114 @@
+package cleaner
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// installCaches are the folders in a node_modules folder that can go without
+// breaking the install, as slash separated globs: build tool caches such as
+// babel's, terser's and esbuild's in .cache and Vite's in .vite, browsers
+// downloaded by puppeteer and playwright, which reinstalling the package
+// downloads again, and node-gyp's intermediate files, left beside the native
+// modules it built.
+var installCaches = []string{
+	".cache",
+	".vite",
+	"puppeteer/.local-chromium",
+	"puppeteer/.local-firefox",
+	"puppeteer-core/.local-chromium",
+	"playwright-core/.local-browsers",
+	"*/build/Release/obj.target",
+	"*/build/Release/.deps",
+	"@*/*/build/Release/obj.target",
+	"@*/*/build/Release/.deps",
+}
+
+// onlyCaches returns the folders to include for f, an accepted target
+// folder: with o.CachesOnly the caches in it if it is a node_modules folder,
+// reporting it as skipped if it has none, otherwise f itself.
+func (o *Options) onlyCaches(fsys fileSystem, f *Folder) []*Folder {
+	if !o.CachesOnly || filepath.Base(f.Path) != NodeModules {
+		return []*Folder{f}
+	}
+	caches := installCacheFolders(fsys, f)
+	if len(caches) == 0 {
+		o.skipped(f, SkipNoCaches)
+	}
+	return caches
+}
+
+// installCacheFolders returns the caches in f, a node_modules folder, each
+// for the same project and with the same age.
+func installCacheFolders(fsys fileSystem, f *Folder) []*Folder {
+	var caches []*Folder
+	for _, pattern := range installCaches {
+		for _, p := range globPath(fsys, f.Path, strings.Split(pattern, "/")) {
+			info, err := fsys.Lstat(p)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			caches = append(caches, &Folder{
+				Path:           p,
+				Project:        f.Project,
+				ModTime:        f.ModTime,
+				ModDaysAgo:     f.ModDaysAgo,
+				PackageManager: f.PackageManager,
+			})
+		}
+	}
+	return caches
+}
+
+// globPath returns the paths below dir matching the globs in parts, one for
+// each folder level.
+func globPath(fsys fileSystem, dir string, parts []string) []string {
+	if len(parts) == 0 {
+		return []string{dir}
+	}
+
+	var names []string
+	if strings.ContainsAny(parts[0], "*?[") {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			return nil
+		}
+		for _, e := range entries {
+			if ok, _ := filepath.Match(parts[0], e.Name()); ok && e.IsDir() && e.Type()&fs.ModeSymlink == 0 {
+				names = append(names, e.Name())
+			}
+		}
+	} else {
+		names = []string{parts[0]}
+	}
+
+	var matches []string
+	for _, name := range names {
+		matches = append(matches, globPath(fsys, filepath.Join(dir, name), parts[1:])...)
+	}
+	return matches
+}
+
+// cacheProject returns the project of p if it is one of the caches in a
+// node_modules folder.
+func cacheProject(p string) (string, bool) {
+	slashed := filepath.ToSlash(filepath.Clean(p))
+	i := strings.LastIndex(slashed, "/"+NodeModules+"/")
+	if i < 0 {
+		return "", false
+	}
+
+	rel := slashed[i+len(NodeModules)+2:]
+	for _, pattern := range installCaches {
+		if ok, _ := path.Match(pattern, rel); ok {
+			project := slashed[:i]
+			if project == "" || strings.HasSuffix(project, ":") {
+				project += "/"
+			}
+			return filepath.FromSlash(project), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,183 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const journalFile = "journal.json"
+
+// The states of a folder in a Journal.
+const (
+	JournalPlanned  = "planned"
+	JournalRemoving = "removing"
+	JournalRemoved  = "removed"
+	JournalFailed   = "failed"
+)
+
+// Journal is the record of a deletion run, kept while it runs and removed
+// once it is over, so one that is left behind by a run that is no longer
+// going is from a run that was killed.
+type Journal struct {
+	// Owner is the run it is the record of.
+	Owner   Owner           `json:"owner"`
+	Started time.Time       `json:"started"`
+	Folders []JournalFolder `json:"folders"`
+}
+
+// JournalFolder is a folder a run set out to delete and how far it got.
+type JournalFolder struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"sizeBytes"`
+	State     string `json:"state"`
+}
+
+// journal writes this run's Journal to the user's cache folder after every
+// change. The file holds the journals of every run deleting at the time, so
+// it is only read and written with the state lock held.
+type journal struct {
+	mu    sync.Mutex
+	j     Journal
+	index map[string]int
+}
+
+// startJournal records that folders are about to be deleted. It is nil if the
+// journal can't be written, in which case nothing is recorded.
+func startJournal(folders []*Folder) *journal {
+	o, err := thisRun()
+	if err != nil {
+		return nil
+	}
+
+	j := &journal{index: make(map[string]int, len(folders))}
+	j.j.Owner = o
+	j.j.Started = time.Now()
+	for i, f := range folders {
+		j.j.Folders = append(j.j.Folders, JournalFolder{Path: f.Path, SizeBytes: f.SizeBytes, State: JournalPlanned})
+		j.index[f.Path] = i
+	}
+	if err := j.save(); err != nil {
+		return nil
+	}
+	return j
+}
+
+// set records the state of the folder at path.
+func (j *journal) set(path, state string) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.j.Folders[j.index[path]].State = state
+	_ = j.save()
+}
+
+// finish removes the journal, as the run is over.
+func (j *journal) finish() {
+	if j != nil {
+		_ = updateJournals(func(_ string, journals []Journal) ([]Journal, error) {
+			return withoutJournal(journals, j.j.Owner), nil
+		})
+	}
+}
+
+func (j *journal) save() error {
+	return updateJournals(func(_ string, journals []Journal) ([]Journal, error) {
+		return append(withoutJournal(journals, j.j.Owner), j.j), nil
+	})
+}
+
+// updateJournals calls fn with the journals in the cache folder and saves
+// those it returns, unless it returns an error.
+func updateJournals(fn func(dir string, journals []Journal) ([]Journal, error)) error {
+	return withStateLock(func(dir string) error {
+		p := filepath.Join(dir, journalFile)
+		var journals []Journal
+		if data, err := os.ReadFile(p); err == nil {
+			if json.Unmarshal(data, &journals) != nil {
+				// A journal from before runs were recorded in it, with
+				// no owner.
+				var j Journal
+				if json.Unmarshal(data, &j) == nil {
+					journals = []Journal{j}
+				}
+			}
+		}
+
+		journals, err := fn(dir, journals)
+		if err != nil {
+			return err
+		}
+		if len(journals) == 0 {
+			err := os.Remove(p)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		data, err := json.Marshal(journals)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(p, data, 0o644)
+	})
+}
+
+// withoutJournal returns journals without that of the run o.
+func withoutJournal(journals []Journal, o Owner) []Journal {
+	kept := journals[:0]
+	for _, j := range journals {
+		if !j.Owner.is(o) {
+			kept = append(kept, j)
+		}
+	}
+	return kept
+}
+
+// InterruptedRuns returns the journals left by deletion runs that were
+// killed. Those of runs still going are left to them. A folder a run was
+// removing when it died is marked removed if nothing is left of it, since
+// the run may have got no further than finishing it.
+func InterruptedRuns() []Journal {
+	renamed := make(map[string]bool)
+	for _, e := range PendingDeletions() {
+		renamed[e.Original] = true
+	}
+
+	var runs []Journal
+	_ = updateJournals(func(dir string, journals []Journal) ([]Journal, error) {
+		for _, j := range journals {
+			if !j.Owner.alive(dir) {
+				runs = append(runs, j)
+			}
+		}
+		return journals, nil
+	})
+
+	for _, j := range runs {
+		for i, f := range j.Folders {
+			if f.State != JournalRemoving || renamed[f.Path] {
+				continue
+			}
+			if _, err := os.Lstat(f.Path); errors.Is(err, fs.ErrNotExist) {
+				j.Folders[i].State = JournalRemoved
+			}
+		}
+	}
+	return runs
+}
+
+// ClearInterruptedRun forgets run, as returned by InterruptedRuns, once it
+// has been reported.
+func ClearInterruptedRun(run Journal) {
+	_ = updateJournals(func(_ string, journals []Journal) ([]Journal, error) {
+		return withoutJournal(journals, run.Owner), nil
+	})
+}
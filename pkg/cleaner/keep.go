@@ -0,0 +1,47 @@
+package cleaner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	KeepMarker    = ".npmcleaner-keep"
+	ProjectConfig = ".npmcleaner.yml"
+)
+
+var errKept = errors.New("project is marked to be kept")
+
+// isKept reports whether project has opted out of cleaning, either with a
+// .npmcleaner-keep file or "keep: true" in a .npmcleaner.yml file.
+func isKept(fsys fileSystem, project string) bool {
+	if _, err := fsys.Stat(filepath.Join(project, KeepMarker)); err == nil {
+		return true
+	}
+
+	data, err := fsys.ReadFile(filepath.Join(project, ProjectConfig))
+	if err != nil {
+		return false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "keep" {
+			switch strings.ToLower(strings.Trim(strings.TrimSpace(value), `"'`)) {
+			case "true", "yes", "on":
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isLocked reports whether err is from a file being in use by another
+// process, which is rare outside Windows.
+func isLocked(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.ETXTBSY)
+}
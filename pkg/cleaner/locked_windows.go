@@ -0,0 +1,30 @@
+package cleaner
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	errorAccessDenied     syscall.Errno = 5
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+	errorDirNotEmpty      syscall.Errno = 145
+)
+
+// isLocked reports whether err is from a file being held open by another
+// process, usually briefly by an editor, a TypeScript server or a virus
+// scanner. Access denied and directory not empty are included as Windows
+// returns them for files that are open or waiting to be deleted.
+func isLocked(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+
+	switch errno {
+	case errorAccessDenied, errorSharingViolation, errorLockViolation, errorDirNotEmpty:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package cleaner
+
+// longPath returns p unchanged, as only Windows limits path lengths.
+func longPath(p string) string {
+	return p
+}
@@ -0,0 +1,30 @@
+package cleaner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxShortPath is the longest path Windows accepts without the extended
+// length prefix. It is less than MAX_PATH as a folder's path must leave room
+// for an 8.3 file name below it.
+const maxShortPath = 247
+
+// longPath returns p with the \\?\ prefix if it is too long for the usual
+// Windows APIs, which is common deep inside node_modules. Extended length
+// paths aren't cleaned by Windows, so p is made absolute and cleaned first.
+func longPath(p string) string {
+	if len(p) <= maxShortPath || strings.HasPrefix(p, `\\?\`) || strings.HasPrefix(p, `\\.\`) {
+		return p
+	}
+
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
@@ -0,0 +1,48 @@
+package cleaner
+
+import "syscall"
+
+const (
+	mntNoWait = 2
+	mntLocal  = 0x1000
+)
+
+// networkMounts returns the folders that filesystems not on this machine,
+// such as SMB, AFP and NFS shares, are mounted on.
+func networkMounts() map[string]bool {
+	mounts := make(map[string]bool)
+	n, err := syscall.Getfsstat(nil, mntNoWait)
+	if err != nil {
+		return mounts
+	}
+
+	stats := make([]syscall.Statfs_t, n)
+	n, err = syscall.Getfsstat(stats, mntNoWait)
+	if err != nil {
+		return mounts
+	}
+
+	for _, st := range stats[:n] {
+		if st.Flags&mntLocal == 0 {
+			mounts[cString(st.Mntonname[:])] = true
+		}
+	}
+	return mounts
+}
+
+func cString(b []int8) string {
+	s := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == 0 {
+			break
+		}
+		s = append(s, byte(c))
+	}
+	return string(s)
+}
+
+// drvfsMounts returns no folders, as Windows drives are only mounted under
+// WSL.
+func drvfsMounts() map[string]bool {
+	return nil
+}
@@ -0,0 +1,90 @@
+package cleaner
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// networkFilesystems are the filesystem types in /proc/self/mountinfo that
+// are on another machine.
+var networkFilesystems = map[string]bool{
+	"nfs":         true,
+	"nfs4":        true,
+	"cifs":        true,
+	"smb3":        true,
+	"smbfs":       true,
+	"ncpfs":       true,
+	"afs":         true,
+	"ceph":        true,
+	"glusterfs":   true,
+	"davfs":       true,
+	"fuse.sshfs":  true,
+	"fuse.rclone": true,
+	"fuse.s3fs":   true,
+}
+
+// networkMounts returns the folders that network filesystems are mounted on.
+func networkMounts() map[string]bool {
+	return mountsWhere(func(fstype, superOptions string) bool {
+		return networkFilesystems[fstype]
+	})
+}
+
+// drvfsMounts returns the folders that Windows drives are mounted on when
+// running under WSL, such as /mnt/c. WSL 1 mounts them as drvfs and WSL 2 as
+// 9p with an aname of drvfs.
+func drvfsMounts() map[string]bool {
+	return mountsWhere(func(fstype, superOptions string) bool {
+		return fstype == "drvfs" || fstype == "9p" && strings.Contains(superOptions, "aname=drvfs")
+	})
+}
+
+// mountsWhere returns the mount points in /proc/self/mountinfo whose
+// filesystem type and options match.
+func mountsWhere(match func(fstype, superOptions string) bool) map[string]bool {
+	mounts := make(map[string]bool)
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return mounts
+	}
+	defer f.Close()
+
+	// Each line is "id parent major:minor root mountpoint options... - type
+	// source superoptions", with spaces in paths escaped as \040.
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+3 < len(fields) && len(fields) > 4 {
+				if match(fields[i+1], fields[i+3]) {
+					mounts[unescapeMount(fields[4])] = true
+				}
+				break
+			}
+		}
+	}
+	return mounts
+}
+
+// unescapeMount decodes the octal escapes the kernel uses for spaces, tabs,
+// newlines and backslashes in mount points.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
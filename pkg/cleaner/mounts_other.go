@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package cleaner
+
+// networkMounts returns no folders, as network shares either can't be found
+// or, on Windows, are drives of their own rather than mounted in a folder.
+func networkMounts() map[string]bool {
+	return nil
+}
+
+// drvfsMounts returns no folders, as Windows drives are only mounted under
+// WSL.
+func drvfsMounts() map[string]bool {
+	return nil
+}
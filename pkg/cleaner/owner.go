@@ -0,0 +1,153 @@
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	stateLockFile = "state.lock"
+	runsDir       = "runs"
+)
+
+// Owner is the run that wrote a record to the user's cache folder, such as a
+// folder it renamed for removal, so other runs leave the record alone while
+// it is still going.
+type Owner struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+}
+
+func (o Owner) is(other Owner) bool {
+	return o.PID == other.PID && o.Started.Equal(other.Started)
+}
+
+var (
+	ownerOnce sync.Once
+	owner     Owner
+	ownerFile *os.File
+	ownerErr  error
+)
+
+func stateDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "npm-cleaner"), nil
+}
+
+// thisRun returns the Owner of the records written by this process. It holds
+// a lock on a file named after it in the cache folder for as long as the
+// process runs, which is how other runs tell it is still alive, even once its
+// PID has been reused. It mustn't be called with the state lock held.
+func thisRun() (Owner, error) {
+	ownerOnce.Do(func() {
+		ownerErr = withStateLock(func(dir string) error {
+			o := Owner{PID: os.Getpid(), Started: time.Now()}
+			p := o.lockPath(dir)
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE, 0o644)
+			if err != nil {
+				return err
+			}
+			locked, err := TryLock(f)
+			if err == nil && !locked {
+				err = fmt.Errorf("%s is locked by another run", p)
+			}
+			if err != nil {
+				_ = f.Close()
+				return err
+			}
+			owner, ownerFile = o, f
+			return nil
+		})
+	})
+	return owner, ownerErr
+}
+
+func (o Owner) lockPath(dir string) string {
+	return filepath.Join(dir, runsDir, fmt.Sprintf("%d-%d.lock", o.PID, o.Started.UnixNano()))
+}
+
+// alive reports whether the run o is still going. Records from before runs
+// were recorded have no owner, and are taken to be from one that is over. If
+// it can't be told, o is taken to be alive, so its records are left alone.
+// It must be called with the state lock held.
+func (o Owner) alive(dir string) bool {
+	if o.PID == 0 {
+		return false
+	}
+	if ownerFile != nil && o.is(owner) {
+		return true
+	}
+
+	p := o.lockPath(dir)
+	f, err := os.OpenFile(p, os.O_RDWR, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	locked, err := TryLock(f)
+	_ = f.Close()
+	if err != nil || !locked {
+		return true
+	}
+	_ = os.Remove(p)
+	return false
+}
+
+// sweepRuns removes the lock files of runs that are over. It must be called
+// with the state lock held.
+func sweepRuns(dir string) {
+	entries, err := os.ReadDir(filepath.Join(dir, runsDir))
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		var o Owner
+		var started int64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(e.Name(), ".lock"), "%d-%d", &o.PID, &started); err != nil {
+			continue
+		}
+		o.Started = time.Unix(0, started)
+		o.alive(dir)
+	}
+}
+
+var stateMu sync.Mutex
+
+// withStateLock calls fn with the cache folder the runs share, holding a lock
+// on it so no other run reads or writes the records in it at the same time.
+func withStateLock(fn func(dir string) error) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	f, err := os.OpenFile(filepath.Join(dir, stateLockFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := Lock(f); err != nil {
+		return err
+	}
+	defer func() { _ = Unlock(f) }()
+	return fn(dir)
+}
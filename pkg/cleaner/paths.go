@@ -0,0 +1,99 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+var (
+	errNotTarget = errors.New("not a target folder or a project with one")
+	errExcluded  = errors.New("in a folder that is always skipped or excluded")
+
+	errChangedSincePlan = errors.New("project modified since the plan was made")
+)
+
+// pathCandidates returns the target folders for o.Paths, in place of
+// discover. A path that is a target folder is used as it is, and a project
+// folder stands for the target folders directly inside it. Paths that don't
+// exist, aren't either, or are excluded are added to results' errors.
+func pathCandidates(ctx context.Context, o *Options, results *Result) ([]*Folder, error) {
+	fsys := o.files()
+	seen := make(map[string]bool)
+	var candidates []*Folder
+	for _, p := range o.Paths {
+		if err := ctx.Err(); err != nil {
+			return candidates, err
+		}
+
+		if o.real() {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+		}
+		if _, err := fsys.Lstat(p); err != nil {
+			results.addError(o, &Folder{Path: p}, err)
+			continue
+		}
+
+		folders := pathTargets(fsys, o.Targets, p)
+		if project, ok := cacheProject(p); ok && o.CachesOnly && len(folders) == 0 {
+			folders = []*Folder{{Path: p, Project: project}}
+		}
+		if len(folders) == 0 {
+			results.addError(o, &Folder{Path: p}, errNotTarget)
+			continue
+		}
+
+		for _, f := range folders {
+			if seen[f.Path] {
+				continue
+			}
+			seen[f.Path] = true
+			results.sawTarget()
+
+			info, err := fsys.Lstat(f.Path)
+			if err != nil {
+				results.addError(o, f, err)
+				continue
+			}
+			if skipFolder(o, f.Path, f.Path, fs.FileInfoToDirEntry(info)) {
+				results.addError(o, f, errExcluded)
+				continue
+			}
+
+			accepted, err := acceptCandidate(o, f)
+			if err != nil {
+				results.addError(o, f, err)
+				continue
+			}
+			if accepted {
+				candidates = append(candidates, o.onlyCaches(fsys, f)...)
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+// pathTargets returns p as a folder if it is a target folder, otherwise the
+// target folders directly inside it.
+func pathTargets(fsys fileSystem, targets []Target, p string) []*Folder {
+	if project, ok := matchTarget(fsys, targets, p); ok {
+		return []*Folder{{Path: p, Project: project, Artifact: isArtifact(targets, p)}}
+	}
+
+	var folders []*Folder
+	for _, t := range targets {
+		path := filepath.Join(p, t.name)
+		info, err := fsys.Lstat(path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if project, ok := matchTarget(fsys, targets, path); ok {
+			folders = append(folders, &Folder{Path: path, Project: project, Artifact: isArtifact(targets, path)})
+		}
+	}
+	return folders
+}
@@ -0,0 +1,259 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pendingMarker is in the name a folder is renamed to while it is removed,
+// e.g. .node_modules.deleting-lq2x9k0, so its project looks clean at once.
+const pendingMarker = ".deleting-"
+
+const pendingFile = "pending.json"
+
+var (
+	errReleasedGone = errors.New("moved away by another run before it could be removed")
+	errTakenOver    = errors.New("taken over by another run")
+)
+
+// PendingDeletion is a folder that was renamed to be removed by a run that
+// didn't finish, such as one that was killed.
+type PendingDeletion struct {
+	// Path is where the folder was renamed to.
+	Path string `json:"path"`
+	// Original is where it was before.
+	Original string `json:"original"`
+	// Removing is set once removal has started, so the folder is probably
+	// only partly there.
+	Removing bool `json:"removing"`
+	// Owner is the run that renamed it, or took it over once that run was
+	// over.
+	Owner Owner `json:"owner"`
+}
+
+// pendingList records the folders renamed for removal in the user's cache
+// folder, so an interrupted run can be finished or rolled back by the next.
+// The file is shared by every run, so it is only read and written with the
+// state lock held, and each run only touches its own entries and those of
+// runs that are over.
+type pendingList struct {
+	owner Owner
+}
+
+// loadPending returns the pending list for this run. It is nil if there is
+// nowhere to keep it, in which case folders are removed without being
+// renamed first.
+func loadPending() *pendingList {
+	o, err := thisRun()
+	if err != nil {
+		return nil
+	}
+	return &pendingList{owner: o}
+}
+
+// update calls fn with the entries in the list, by path, and saves the
+// changes it makes unless it returns an error.
+func (l *pendingList) update(fn func(dir string, entries map[string]PendingDeletion) error) error {
+	return withStateLock(func(dir string) error {
+		p := filepath.Join(dir, pendingFile)
+		entries := make(map[string]PendingDeletion)
+		if data, err := os.ReadFile(p); err == nil {
+			var list []PendingDeletion
+			if json.Unmarshal(data, &list) == nil {
+				for _, e := range list {
+					entries[e.Path] = e
+				}
+			}
+		}
+		if err := fn(dir, entries); err != nil {
+			return err
+		}
+		return savePending(p, entries)
+	})
+}
+
+func savePending(p string, entries map[string]PendingDeletion) error {
+	if len(entries) == 0 {
+		err := os.Remove(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	list := make([]PendingDeletion, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+// set records e as this run's.
+func (l *pendingList) set(e PendingDeletion) error {
+	e.Owner = l.owner
+	return l.update(func(_ string, entries map[string]PendingDeletion) error {
+		entries[e.Path] = e
+		return nil
+	})
+}
+
+func (l *pendingList) remove(p string) {
+	_ = l.update(func(_ string, entries map[string]PendingDeletion) error {
+		delete(entries, p)
+		return nil
+	})
+}
+
+// startRemoving records that removing the folder released to e.Path has
+// started. It fails if the folder is no longer there, as when another run
+// has put it back, so it isn't reported as deleted.
+func (l *pendingList) startRemoving(e PendingDeletion) error {
+	e.Owner = l.owner
+	e.Removing = true
+	return l.update(func(_ string, entries map[string]PendingDeletion) error {
+		if _, err := os.Lstat(e.Path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return &fs.PathError{Op: "remove", Path: e.Original, Err: errReleasedGone}
+			}
+			return err
+		}
+		entries[e.Path] = e
+		return nil
+	})
+}
+
+// claim takes e over for this run, if it is still pending and the run that
+// renamed it is over, so no other run finishes or rolls it back as well.
+func (l *pendingList) claim(e PendingDeletion) bool {
+	claimed := false
+	_ = l.update(func(dir string, entries map[string]PendingDeletion) error {
+		cur, ok := entries[e.Path]
+		if !ok || !cur.Owner.is(l.owner) && cur.Owner.alive(dir) {
+			return nil
+		}
+		cur.Owner = l.owner
+		entries[e.Path] = cur
+		claimed = true
+		return nil
+	})
+	return claimed
+}
+
+// release renames the folder at p out of the way so its project is clean at
+// once, recording it so it can be finished or rolled back if the run is
+// interrupted. It returns the new path, or p if it couldn't be renamed, as
+// when a file in it is locked on Windows, leaving it to be removed where it
+// is.
+func (l *pendingList) release(p string) string {
+	if l == nil {
+		return p
+	}
+
+	to := filepath.Join(filepath.Dir(p), "."+filepath.Base(p)+pendingMarker+strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := l.set(PendingDeletion{Path: to, Original: p}); err != nil {
+		return p
+	}
+	if err := os.Rename(longPath(p), longPath(to)); err != nil {
+		l.remove(to)
+		return p
+	}
+	return to
+}
+
+// restore renames a released folder back to where it was, if nothing has
+// taken its place.
+func (l *pendingList) restore(e PendingDeletion) error {
+	return l.update(func(_ string, entries map[string]PendingDeletion) error {
+		if _, err := os.Lstat(e.Original); err == nil {
+			return &fs.PathError{Op: "restore", Path: e.Original, Err: fs.ErrExist}
+		}
+		if err := os.Rename(longPath(e.Path), longPath(e.Original)); err != nil {
+			return err
+		}
+		delete(entries, e.Path)
+		return nil
+	})
+}
+
+// isPending reports whether name is that of a folder being removed.
+func isPending(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, pendingMarker)
+}
+
+// PendingDeletions returns the folders left renamed for removal by runs that
+// didn't finish. Those of runs still going are left to them, and those that
+// no longer exist are forgotten.
+func PendingDeletions() []PendingDeletion {
+	l := loadPending()
+	if l == nil {
+		return nil
+	}
+
+	var pending []PendingDeletion
+	_ = l.update(func(dir string, entries map[string]PendingDeletion) error {
+		for p, e := range entries {
+			if e.Owner.alive(dir) {
+				continue
+			}
+			if _, err := os.Lstat(p); errors.Is(err, fs.ErrNotExist) {
+				delete(entries, p)
+				continue
+			}
+			pending = append(pending, e)
+		}
+		sweepRuns(dir)
+		return nil
+	})
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Original < pending[j].Original })
+	return pending
+}
+
+// FinishPending removes the folders in pending, as returned by
+// PendingDeletions.
+func FinishPending(pending []PendingDeletion, opts Options) []DeleteResult {
+	l := loadPending()
+	out := make([]DeleteResult, 0, len(pending))
+	for _, e := range pending {
+		r := DeleteResult{Path: e.Original}
+		fsys := osFS{remover: opts.Remover}
+		if l == nil || !l.claim(e) {
+			r.Err = errTakenOver
+		} else if err := removeAndVerify(fsys, e.Path, fsys.RemoveAll, &opts); err != nil {
+			r.Err = err
+		} else {
+			r.Deleted = true
+			l.remove(e.Path)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// RollbackPending renames the folders in pending, as returned by
+// PendingDeletions, back to where they were. One whose removal had started
+// is likely to be missing files.
+func RollbackPending(pending []PendingDeletion) []DeleteResult {
+	l := loadPending()
+	out := make([]DeleteResult, 0, len(pending))
+	for _, e := range pending {
+		r := DeleteResult{Path: e.Original}
+		if l == nil || !l.claim(e) {
+			r.Err = errTakenOver
+		} else {
+			r.Err = l.restore(e)
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package cleaner
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// The package managers DetectPackageManager recognises.
+const (
+	PackageManagerNPM  = "npm"
+	PackageManagerYarn = "yarn"
+	PackageManagerPNPM = "pnpm"
+	PackageManagerBun  = "bun"
+)
+
+// lockfiles are checked in order, so a project that has moved to pnpm or
+// yarn and still has an old package-lock.json lying around is taken to use
+// the newer one.
+var lockfiles = []struct {
+	name    string
+	manager string
+}{
+	{"pnpm-lock.yaml", PackageManagerPNPM},
+	{"bun.lockb", PackageManagerBun},
+	{"bun.lock", PackageManagerBun},
+	{"yarn.lock", PackageManagerYarn},
+	{"package-lock.json", PackageManagerNPM},
+	{"npm-shrinkwrap.json", PackageManagerNPM},
+}
+
+// DetectPackageManager returns the package manager whose lockfile is in
+// project, or "" if there is none.
+func DetectPackageManager(project string) string {
+	return detectPackageManager(osFS{}, project)
+}
+
+func detectPackageManager(fsys fileSystem, project string) string {
+	if project == "" {
+		return ""
+	}
+	for _, l := range lockfiles {
+		if _, err := fsys.Lstat(filepath.Join(project, l.name)); err == nil {
+			return l.manager
+		}
+	}
+	return ""
+}
+
+// InstallCommand returns the command that reinstalls project's dependencies
+// with manager, exactly as its lockfile records them. With no package manager
+// it is npm install, which makes a new lockfile.
+func InstallCommand(project, manager string) []string {
+	switch manager {
+	case PackageManagerNPM:
+		return []string{"npm", "ci"}
+	case PackageManagerPNPM:
+		return []string{"pnpm", "install", "--frozen-lockfile"}
+	case PackageManagerBun:
+		return []string{"bun", "install", "--frozen-lockfile"}
+	case PackageManagerYarn:
+		if isYarnBerry(project) {
+			return []string{"yarn", "install", "--immutable"}
+		}
+		return []string{"yarn", "install", "--frozen-lockfile"}
+	}
+	return []string{"npm", "install"}
+}
+
+// isYarnBerry reports whether project uses Yarn 2 or later, which replaced
+// --frozen-lockfile with --immutable. Its lockfile starts with __metadata.
+func isYarnBerry(project string) bool {
+	fsys := osFS{}
+	if _, err := fsys.Lstat(filepath.Join(project, ".yarnrc.yml")); err == nil {
+		return true
+	}
+	data, err := fsys.ReadFile(filepath.Join(project, "yarn.lock"))
+	return err == nil && bytes.Contains(data, []byte("\n__metadata:"))
+}
+
+// countPackages returns how many packages are installed in the node_modules
+// folder at path: each folder directly inside it, or inside an @scope folder.
+// Folders starting with a dot, such as .bin and .cache, aren't packages,
+// except that pnpm keeps every package it installed in .pnpm with only the
+// project's own dependencies linked beside it, so those are counted instead.
+// Other folders are 0.
+func countPackages(fsys fileSystem, path string) int {
+	if filepath.Base(path) != "node_modules" {
+		return 0
+	}
+
+	if entries, err := fsys.ReadDir(filepath.Join(path, ".pnpm")); err == nil {
+		n := 0
+		for _, e := range entries {
+			if e.IsDir() && e.Name() != "node_modules" {
+				n++
+			}
+		}
+		return n
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || !(e.IsDir() || isLink(e)) {
+			continue
+		}
+		if !strings.HasPrefix(name, "@") {
+			n++
+			continue
+		}
+		scoped, err := fsys.ReadDir(filepath.Join(path, name))
+		if err != nil {
+			continue
+		}
+		for _, s := range scoped {
+			if s.IsDir() || isLink(s) {
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package cleaner
+
+import "io/fs"
+
+// isPlaceholder always reports false, as cloud files placeholders are
+// Windows only.
+func isPlaceholder(d fs.DirEntry) bool {
+	return false
+}
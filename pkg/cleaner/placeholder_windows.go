@@ -0,0 +1,27 @@
+package cleaner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+const (
+	fileAttributeOffline            = 0x1000
+	fileAttributeRecallOnOpen       = 0x40000
+	fileAttributeRecallOnDataAccess = 0x400000
+)
+
+// isPlaceholder reports whether d is a cloud files placeholder, as used by
+// OneDrive, Dropbox and Google Drive, whose contents are downloaded when
+// they are read.
+func isPlaceholder(d fs.DirEntry) bool {
+	info, err := d.Info()
+	if err != nil {
+		return false
+	}
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return false
+	}
+	return attrs.FileAttributes&(fileAttributeOffline|fileAttributeRecallOnOpen|fileAttributeRecallOnDataAccess) != 0
+}
@@ -0,0 +1,153 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Project is a folder with a package.json, found by FindProjects whatever its
+// age or size.
+type Project struct {
+	Path string
+	// NodeModules is set if the project has a node_modules folder, and
+	// NodeModulesBytes is its size.
+	NodeModules      bool
+	NodeModulesBytes int64
+	// PackageManager is the package manager whose lockfile is in the
+	// project, e.g. PackageManagerNPM, or "" if there is none.
+	PackageManager string
+	// LastActivity is when the project was last modified, according to
+	// o.AgeSource.
+	LastActivity time.Time
+	ModDaysAgo   int
+}
+
+// FindProjects lists every folder with a package.json below the start folders
+// in opts, whether or not it has anything to delete, sorted by opts.SortBy.
+// Folders that can't be read are skipped with a warning, and projects inside
+// target folders, such as the packages in node_modules, aren't included.
+func FindProjects(ctx context.Context, opts Options) ([]*Project, error) {
+	o := &opts
+	fsys := o.files()
+
+	var mu sync.Mutex
+	var projects []*Project
+	seen := make(map[string]bool)
+
+	roots := startDirs(o)
+	remote := remoteSkipper(o, roots)
+	links := o.linkFollower()
+	for _, root := range roots {
+		onDevice := sameDevice(fsys, o, root)
+		err := walkDirParallel(fsys, root, o.Workers, links, func(path string, d fs.DirEntry, err error) error {
+			if d == nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err != nil {
+				o.warn(err)
+				return fs.SkipDir
+			}
+			if !d.IsDir() || isLink(d) {
+				return nil
+			}
+			if d.Name() == ".git" || skipFolder(o, root, path, d) || o.tooDeep(root, path) || !onDevice(d) || remote(path, d) {
+				return fs.SkipDir
+			}
+			if _, ok := matchTarget(fsys, o.Targets, path); ok {
+				return fs.SkipDir
+			}
+
+			if _, err := fsys.Stat(filepath.Join(path, PackageJSON)); err != nil {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !seen[path] {
+				seen[path] = true
+				projects = append(projects, &Project{Path: path})
+				o.progress("Scanning, %d projects found: %s", len(projects), path)
+			}
+			return nil
+		})
+		if err != nil {
+			o.progressDone()
+			return nil, err
+		}
+	}
+
+	for i, p := range projects {
+		o.progress("Sizing %d/%d projects: %s", i+1, len(projects), p.Path)
+		if err := inspectProject(ctx, o, p); err != nil {
+			if ctx.Err() != nil {
+				o.progressDone()
+				return nil, ctx.Err()
+			}
+			o.warn(err)
+		}
+	}
+	o.progressDone()
+
+	sortProjects(projects, o.SortBy, o.Reverse)
+	return projects, nil
+}
+
+// inspectProject fills in what is known about p's node_modules, lockfile and
+// last activity.
+func inspectProject(ctx context.Context, o *Options, p *Project) error {
+	fsys := o.files()
+	p.PackageManager = detectPackageManager(fsys, p.Path)
+
+	nodeModules := filepath.Join(p.Path, "node_modules")
+	info, err := fsys.Lstat(nodeModules)
+	p.NodeModules = err == nil && info.IsDir()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if p.NodeModules {
+		u, err := folderSize(ctx, fsys, nodeModules, o.DiskUsage)
+		if err != nil {
+			return err
+		}
+		p.NodeModulesBytes = u.SizeBytes
+	}
+
+	// A project without node_modules has no target folder to take the age of.
+	ageOf := nodeModules
+	if !p.NodeModules && o.AgeSource == AgeSourceTarget {
+		ageOf = p.Path
+	}
+	modTime, err := projectModTime(o, p.Path, ageOf)
+	if err != nil {
+		return err
+	}
+	p.LastActivity, p.ModDaysAgo = modTime, daysSince(modTime)
+	return nil
+}
+
+// sortProjects sorts projects by the size of their node_modules, least
+// recently active or alphabetically first.
+func sortProjects(projects []*Project, by string, reverse bool) {
+	sort.SliceStable(projects, func(i, j int) bool {
+		a, b := projects[i], projects[j]
+		if reverse {
+			a, b = b, a
+		}
+
+		switch by {
+		case SortAge:
+			return a.LastActivity.Before(b.LastActivity)
+		case SortPath:
+			return a.Path < b.Path
+		default:
+			return a.NodeModulesBytes > b.NodeModulesBytes
+		}
+	})
+}
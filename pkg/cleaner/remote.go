@@ -0,0 +1,79 @@
+package cleaner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cloudFolders are the names of the folders cloud storage clients sync in a
+// user's home folder. Their contents may only be placeholders, which would be
+// downloaded if they were scanned or deleted.
+var cloudFolders = []string{
+	"OneDrive",
+	"OneDrive - *",
+	"Dropbox",
+	"Dropbox (*)",
+	"Google Drive",
+	"My Drive",
+	"iCloudDrive",
+	"iCloud Drive",
+	"Box",
+	"Box Sync",
+}
+
+// remoteSkipper returns a function reporting whether a folder shouldn't be
+// scanned because it is a network mount or synced with a cloud service,
+// unless o.IncludeRemote is set, or is a Windows drive mounted in WSL, unless
+// o.IncludeWindowsDrives is set. Folders are only skipped below the start
+// folders, so scanning one directly still works, and never in a scan of
+// anything but the real filesystem.
+func remoteSkipper(o *Options, roots []string) func(path string, d fs.DirEntry) bool {
+	if !o.real() {
+		return func(string, fs.DirEntry) bool { return false }
+	}
+
+	mounts := make(map[string]bool)
+	if !o.IncludeRemote {
+		for p := range networkMounts() {
+			mounts[p] = true
+		}
+	}
+	if !o.IncludeWindowsDrives {
+		for p := range drvfsMounts() {
+			mounts[p] = true
+		}
+	}
+	for _, root := range roots {
+		delete(mounts, root)
+	}
+
+	home, _ := os.UserHomeDir()
+	isRoot := func(path string) bool {
+		for _, root := range roots {
+			if path == root {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(path string, d fs.DirEntry) bool {
+		if mounts[path] {
+			return true
+		}
+		if o.IncludeRemote || isRoot(path) {
+			return false
+		}
+		return isPlaceholder(d) || home != "" && filepath.Dir(path) == home && isCloudFolder(d.Name())
+	}
+}
+
+func isCloudFolder(name string) bool {
+	for _, pattern := range cloudFolders {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
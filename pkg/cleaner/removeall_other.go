@@ -0,0 +1,11 @@
+//go:build !windows
+
+package cleaner
+
+import "os"
+
+// removeAll is os.RemoveAll. Read-only files don't stop a folder being
+// removed outside Windows.
+func removeAll(p string) error {
+	return os.RemoveAll(p)
+}
@@ -0,0 +1,56 @@
+package cleaner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+const blockingAttributes = syscall.FILE_ATTRIBUTE_READONLY | syscall.FILE_ATTRIBUTE_HIDDEN | syscall.FILE_ATTRIBUTE_SYSTEM
+
+// removeAll is os.RemoveAll, except that links inside p are removed first so
+// nothing can be deleted through a junction, and if it is refused the
+// read-only, hidden and system attributes are cleared from everything under p
+// and it is tried again. Some packages ship read-only files, and git makes
+// its objects read-only, and Windows won't delete them.
+func removeAll(p string) error {
+	removeLinks(p)
+
+	err := os.RemoveAll(p)
+	if err == nil || !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+
+	clearAttributes(p)
+	return os.RemoveAll(p)
+}
+
+// removeLinks removes the symbolic links and junctions below p, leaving what
+// they point to untouched.
+func removeLinks(p string) {
+	_ = walkDir(osFS{}, p, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && path != p && isLink(d) {
+			_ = os.Remove(path)
+		}
+		return nil
+	})
+}
+
+func clearAttributes(p string) {
+	_ = walkDir(osFS{}, p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		name, err := syscall.UTF16PtrFromString(path)
+		if err != nil {
+			return nil
+		}
+		attrs, err := syscall.GetFileAttributes(name)
+		if err == nil && attrs&blockingAttributes != 0 {
+			_ = syscall.SetFileAttributes(name, attrs&^blockingAttributes)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errUnsafe = errors.New("refusing to delete")
+
+// checkDeletable makes sure the folder at path, released from original if
+// that is different, is still safe to remove just before it is: it must
+// still be a folder rather than a link, named as one of o.Targets, or with
+// o.CachesOnly one of the caches in a node_modules folder, and below one of
+// the start folders, and never the root of a filesystem or a home folder.
+// The global caches of o.Caches are only checked for being folders, as they
+// are found by name rather than by scanning.
+func checkDeletable(fsys fileSystem, o *Options, original, path string) error {
+	refuse := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", errUnsafe, fmt.Sprintf(format, args...))
+	}
+
+	if isRoot(original) {
+		return refuse("%s is the root of a filesystem", original)
+	}
+	if o.real() {
+		for _, home := range homeDirs() {
+			if filepath.Clean(original) == filepath.Clean(home) {
+				return refuse("%s is a home folder", original)
+			}
+		}
+	}
+
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return refuse("%s", err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return refuse("%s is now a link", original)
+	}
+	if !info.IsDir() {
+		return refuse("%s is no longer a folder", original)
+	}
+
+	if o.Caches {
+		return nil
+	}
+	if o.CachesOnly {
+		if _, ok := cacheProject(original); !ok {
+			return refuse("%s isn't a cache in node_modules", original)
+		}
+	} else if _, ok := matchTarget(fsys, o.Targets, original); !ok {
+		return refuse("%s isn't named as a target folder", original)
+	}
+	if o.Paths != nil {
+		return nil
+	}
+	for _, dir := range startDirs(o) {
+		if inNodeModules(dir) {
+			return refuse("the start folder %s is inside node_modules", dir)
+		}
+	}
+	for _, dir := range startDirs(o) {
+		if isBelowDir(dir, original) {
+			return nil
+		}
+	}
+	return refuse("%s isn't below a start folder", original)
+}
+
+// homeDirs returns the current user's home folder and those of the other
+// users of the machine.
+func homeDirs() []string {
+	homes, _ := UserHomes()
+	if home, err := os.UserHomeDir(); err == nil {
+		homes = append(homes, home)
+	}
+	return homes
+}
+
+// inNodeModules reports whether dir is a node_modules folder or inside one,
+// where every folder found would be part of an installed package.
+func inNodeModules(dir string) bool {
+	for _, name := range strings.Split(filepath.ToSlash(filepath.Clean(dir)), "/") {
+		if name == "node_modules" {
+			return true
+		}
+	}
+	return false
+}
+
+// isBelowDir reports whether path is strictly below dir.
+func isBelowDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
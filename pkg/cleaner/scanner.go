@@ -0,0 +1,1147 @@
+// Package cleaner finds node_modules and other build folders in projects
+// that haven't been worked on for a while, and deletes them.
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	NodeModules = "node_modules"
+	PackageJSON = "package.json"
+)
+
+var separatorEscaped = regexp.QuoteMeta(string(filepath.Separator))
+
+func matchFolders(folderName string) *regexp.Regexp {
+	regEx := fmt.Sprintf(".*?%s%s%s.*?",
+		separatorEscaped, folderName, separatorEscaped)
+
+	return regexp.MustCompile(regEx)
+}
+
+// matchRootFolders matches a folder directly below the root, and everything
+// below it.
+func matchRootFolders(folderName string) *regexp.Regexp {
+	regEx := fmt.Sprintf("^%s%s(%s.*)?$",
+		separatorEscaped, folderName, separatorEscaped)
+
+	return regexp.MustCompile(regEx)
+}
+
+// isHidden reports whether a folder name marks it as hidden, i.e. it starts
+// with a dot. The "." and ".." entries are not considered hidden.
+func isHidden(name string) bool {
+	return len(name) > 1 && name[0] == '.' && name != ".."
+}
+
+func newResults() *Result {
+	return &Result{
+		Folders: make([]*Folder, 0, DefaultLimit),
+		Stats:   ScanStats{Skipped: make(map[string]int)},
+	}
+}
+
+// Result holds the folders found by a scan.
+type Result struct {
+	mu sync.Mutex
+
+	// Folders are the folders to delete.
+	Folders []*Folder
+	// Review are folders larger than MaxSize, never deleted automatically.
+	Review []*Folder
+	// Dirty are folders whose project has uncommitted or unpushed changes.
+	Dirty []*Folder
+	// Discovered is every folder found by a Histogram scan.
+	Discovered []*Folder
+	// Errors are folders that couldn't be read. Each was skipped and the
+	// scan carried on.
+	Errors    []*ScanError
+	TotalSize int64
+	// TotalReclaimable is how much deleting every folder in Folders frees.
+	TotalReclaimable int64
+	// Stats count what the scan did.
+	Stats ScanStats
+	// Partial is set if the walk stopped at Options.MaxDuration, so only the
+	// folders found by then are included.
+	Partial bool
+}
+
+// ScanError is a folder left out of a scan because something in it couldn't
+// be read, such as a permission error or a file removed while it was sized.
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+func (e *ScanError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+func (r *Result) add(f *Folder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.TotalSize += f.SizeBytes
+	r.TotalReclaimable += f.ReclaimableBytes
+	r.Folders = append(r.Folders, f)
+}
+
+// Keep replaces the found folders with only those given, e.g. after the user
+// has chosen which to delete.
+func (r *Result) Keep(folders []*Folder) {
+	kept := make([]*Folder, len(folders))
+	copy(kept, folders)
+
+	r.Folders = r.Folders[:0]
+	r.TotalSize = 0
+	r.TotalReclaimable = 0
+	for _, f := range kept {
+		r.add(f)
+	}
+}
+
+// addReview records a folder that matched every criteria but is too large to
+// be deleted without a human looking at it first.
+func (r *Result) addReview(f *Folder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Review = append(r.Review, f)
+}
+
+// addDiscovered records a folder found during a -histogram scan, before any
+// age, size or limit filtering is applied.
+func (r *Result) addDiscovered(f *Folder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Discovered = append(r.Discovered, f)
+}
+
+// addError records that f was skipped because of err.
+func (r *Result) addError(o *Options, f *Folder, err error) {
+	o.emit(Event{Kind: Error, Folder: f, Err: err})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Errors = append(r.Errors, &ScanError{Path: f.Path, Err: err})
+}
+
+// addDirty records a folder that matched every criteria but whose project has
+// uncommitted or unpushed changes.
+func (r *Result) addDirty(f *Folder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Dirty = append(r.Dirty, f)
+}
+
+// planFree keeps only the folders that free the most needed to free at least
+// goal bytes, going by ReclaimableBytes as hard-linked files free less than
+// their size. If every folder together is not enough, all are kept.
+func (r *Result) planFree(goal int64) {
+	sorted := make([]*Folder, len(r.Folders))
+	copy(sorted, r.Folders)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ReclaimableBytes > sorted[j].ReclaimableBytes
+	})
+
+	var planned []*Folder
+	var total int64
+	for _, f := range sorted {
+		if total >= goal {
+			break
+		}
+		planned = append(planned, f)
+		total += f.ReclaimableBytes
+	}
+
+	r.Keep(planned)
+}
+
+// dropRecent removes the n most recently modified folders, keeping the rest.
+func (r *Result) dropRecent(n int) {
+	sorted := make([]*Folder, len(r.Folders))
+	copy(sorted, r.Folders)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ModTime.After(sorted[j].ModTime)
+	})
+
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+	r.Keep(sorted[n:])
+}
+
+// truncate keeps only the first n folders, in their current order. n of 0
+// keeps them all.
+func (r *Result) truncate(n int) {
+	if n > 0 && len(r.Folders) > n {
+		r.Keep(r.Folders[:n])
+	}
+}
+
+func (r *Result) sort(by string, reverse bool) {
+	SortFolders(r.Folders, by, reverse)
+	SortFolders(r.Review, by, reverse)
+	SortFolders(r.Dirty, by, reverse)
+}
+
+// SortFolders sorts folders highest scoring, largest, oldest or alphabetically
+// first. Folders with the same score are sorted largest first.
+func SortFolders(folders []*Folder, by string, reverse bool) {
+	sort.SliceStable(folders, func(i, j int) bool {
+		a, b := folders[i], folders[j]
+		if reverse {
+			a, b = b, a
+		}
+
+		switch by {
+		case SortScore:
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			return a.SizeBytes > b.SizeBytes
+		case SortAge:
+			return a.ModTime.Before(b.ModTime)
+		case SortPath:
+			return a.Path < b.Path
+		default:
+			return a.SizeBytes > b.SizeBytes
+		}
+	})
+}
+
+// Folder is a target folder found by a scan.
+type Folder struct {
+	Path      string
+	Project   string
+	SizeBytes int64
+	// ReclaimableBytes is how much deleting the folder frees. It is less than
+	// SizeBytes when files are hard linked from outside the folder, as pnpm
+	// does from its store.
+	ReclaimableBytes int64
+	ModTime          time.Time
+	ModDaysAgo       int
+	// PackageManager is the package manager whose lockfile is in the
+	// project, e.g. PackageManagerNPM, or "" if there is none.
+	PackageManager string
+	// PackageCount is how many packages are installed in a node_modules
+	// folder, a better guide than its size to how long reinstalling takes.
+	PackageCount int
+	// Members are the folders of the packages of a workspace, for a folder
+	// in its root. They are counted in its size and age, and deleted with it.
+	Members []string
+	// Score is how good a candidate for deleting the folder is, from 0 to
+	// 100, set when sorting by SortScore. See ScoreWeights.
+	Score float64
+	// Artifact is set for build output, such as dist, rather than installed
+	// packages. It has its own thresholds.
+	Artifact bool
+}
+
+func (f *Folder) setUsage(u usage) {
+	f.SizeBytes = u.SizeBytes
+	f.ReclaimableBytes = u.ReclaimableBytes
+}
+
+// Options controls what a Scanner looks for and how Delete removes folders.
+// Start from DefaultOptions and change what is needed.
+type Options struct {
+	OlderThan time.Duration
+	AgeSource string
+	SkipDirty bool
+
+	OrphansOnly bool
+	FreeGoal    int64
+	KeepRecent  int
+	SortBy      string
+	// ScoreWeights are how much each part of the score counts when sorting
+	// by SortScore.
+	ScoreWeights ScoreWeights
+	Reverse      bool
+	Workers      int
+	SizeCache    bool
+	UseIndex     bool
+	// Paths, if not nil, are the folders to look at instead of scanning. Each is
+	// a target folder, or a project folder standing for the target folders
+	// directly inside it. The same checks apply as to folders found by a
+	// scan.
+	Paths []string
+	// Exact includes every folder in Paths whatever its age or size.
+	Exact bool
+	// SplitWorkspaces lists the folders of workspace packages on their own,
+	// rather than as members of the folder in the workspace root.
+	SplitWorkspaces bool
+	// PlannedModTimes, if set, holds when each folder's project was last
+	// modified at the time a plan was made. A folder whose project has been
+	// modified since is skipped with an error rather than deleted.
+	PlannedModTimes map[string]time.Time
+	DiskUsage       bool
+	MinSize         int64
+	MaxSize         int64
+	// ArtifactMinSize and ArtifactOlderThan, if not 0, are the thresholds
+	// for build output, such as dist, instead of MinSize and OlderThan.
+	ArtifactMinSize   int64
+	ArtifactOlderThan time.Duration
+	Limit             int
+	FromDir           string
+	// ExtraDirs are more folders to scan along with FromDir, with the
+	// results merged.
+	ExtraDirs     []string
+	OneFileSystem bool
+	// MaxDuration, if not 0, is how long the walk looks for folders before
+	// stopping, so a slow disk can't hold up a scheduled run. The folders
+	// found by then are still sized and the Result is marked Partial.
+	MaxDuration time.Duration
+	// CachesOnly includes the caches, downloaded browsers and native build
+	// files in each node_modules folder found, which can go without breaking
+	// the install, instead of the folder itself.
+	CachesOnly bool
+	// FollowSymlinks walks into links to folders, such as a projects folder
+	// linked from another volume. A folder reached more than once, through a
+	// link or otherwise, is only scanned the first time.
+	FollowSymlinks bool
+	IncludeRemote  bool
+	// IncludeWindowsDrives scans Windows drives mounted in WSL, such as
+	// /mnt/c, which are slow to scan and usually cleaned from Windows.
+	IncludeWindowsDrives bool
+	// IncludeWSL also scans the home folders of WSL distributions when run
+	// on Windows.
+	IncludeWSL bool
+	// IncludeLibrary scans the Library folders, Time Machine snapshots and
+	// backups, and app bundles skipped by default on macOS.
+	IncludeLibrary bool
+	// AllDrives scans every fixed drive instead of FromDir on Windows.
+	AllDrives bool
+	// PerUser scans each user's home folder, as returned by UserHomes,
+	// instead of FromDir.
+	PerUser bool
+	// MaxDepth, if not 0, is how many folders below a start folder projects
+	// are looked for. The target folders directly inside the deepest are
+	// still found.
+	MaxDepth   int
+	SkipHidden bool
+	Histogram  bool
+	Caches     bool
+
+	DeleteOrder string
+	// DeleteWorkers is how many folders Delete removes at once.
+	DeleteWorkers int
+	VerifyRetries int
+	LockRetries   int
+	Trash         bool
+	// ArchiveDir, if set, is where each folder is written to a tar archive
+	// before it is deleted, compressed as ArchiveCompress says.
+	ArchiveDir      string
+	ArchiveCompress string
+
+	Targets  []Target
+	Excludes []*regexp.Regexp
+
+	// Progress, if set, is called with a short description of what the scan
+	// is doing, and with an empty string when each stage is done.
+	Progress func(status string)
+	// Warn, if set, is called with problems that don't stop the scan.
+	Warn func(err error)
+	// FS, if set, is scanned instead of the real filesystem, e.g. an
+	// fstest.MapFS in tests or a recorded snapshot. Paths, including FromDir,
+	// are from its root with or without a leading slash. Git checks, the
+	// size cache, the trash and watching only work on the real filesystem.
+	FS fs.FS
+	// Remover, if set, deletes folders instead of os.RemoveAll. It is
+	// needed to delete from FS.
+	Remover Remover
+
+	// OnEvent, if set, is called as each folder is found, skipped, sized and
+	// deleted. It may be called from several goroutines at once.
+	OnEvent func(e Event)
+}
+
+const (
+	DefaultLimit     = 10
+	DefaultMinSize   = 50 * MB
+	DefaultOlderThan = 7 * Day
+
+	// DefaultArtifactMinSize is lower than DefaultMinSize as build output is
+	// usually smaller than node_modules, and there can be several in a
+	// project.
+	DefaultArtifactMinSize = 10 * MB
+
+	// DefaultLockRetries retries a folder whose files are locked for up to
+	// about 4 seconds.
+	DefaultLockRetries = 4
+)
+
+const (
+	SortSize = "size"
+	SortAge  = "age"
+	SortPath = "path"
+)
+
+func ValidSort(by string) bool {
+	switch by {
+	case SortScore, SortSize, SortAge, SortPath:
+		return true
+	}
+	return false
+}
+
+// minSize is the smallest f can be to be included: o.ArtifactMinSize for
+// build output if set, otherwise o.MinSize.
+func (o *Options) minSize(f *Folder) int64 {
+	if f.Artifact && o.ArtifactMinSize > 0 {
+		return o.ArtifactMinSize
+	}
+	return o.MinSize
+}
+
+// olderThan is how long f's project must have been left alone for it to be
+// included: o.ArtifactOlderThan for build output if set, otherwise
+// o.OlderThan.
+func (o *Options) olderThan(f *Folder) time.Duration {
+	if f.Artifact && o.ArtifactOlderThan > 0 {
+		return o.ArtifactOlderThan
+	}
+	return o.OlderThan
+}
+
+// ignoreThresholds reports whether the age and size limits are ignored because
+// another rule decides which folders are included.
+func (o *Options) ignoreThresholds() bool {
+	return o.OrphansOnly || o.KeepRecent > 0 || o.Exact
+}
+
+// ignoreLimit reports whether -limit is ignored because another rule decides
+// how many folders are included.
+func (o *Options) ignoreLimit() bool {
+	return o.FreeGoal > 0 || o.KeepRecent > 0 || o.Exact
+}
+
+var DefaultStartDir = string(filepath.Separator)
+
+// DefaultOptions returns the options used when none are given on the command
+// line: node_modules folders of at least 50MB in projects untouched for a
+// week, searched for from the root of the filesystem.
+func DefaultOptions() Options {
+	return Options{
+		OlderThan:       DefaultOlderThan,
+		AgeSource:       AgeSourceProject,
+		SortBy:          SortScore,
+		ScoreWeights:    DefaultScoreWeights,
+		Workers:         runtime.NumCPU(),
+		SizeCache:       true,
+		MinSize:         DefaultMinSize,
+		ArtifactMinSize: DefaultArtifactMinSize,
+		Limit:           DefaultLimit,
+		FromDir:         DefaultStartDir,
+		SkipHidden:      true,
+		DeleteOrder:     DeleteLargestFirst,
+		DeleteWorkers:   DefaultDeleteWorkers,
+		LockRetries:     DefaultLockRetries,
+		ArchiveCompress: ArchiveNone,
+		Targets:         presets[DefaultPreset],
+	}
+}
+
+// Scanner finds target folders according to its Options.
+type Scanner struct {
+	opts Options
+}
+
+// NewScanner returns a Scanner that finds folders according to opts.
+func NewScanner(opts Options) *Scanner {
+	return &Scanner{opts: opts}
+}
+
+// Scan finds the folders that match the scanner's options, without deleting
+// anything. If ctx is cancelled the folders found so far are returned along
+// with ctx's error.
+func (s *Scanner) Scan(ctx context.Context) (*Result, error) {
+	o := &s.opts
+	start := time.Now()
+	var results *Result
+	var err error
+	if o.Caches {
+		results, err = runCaches(ctx, o)
+	} else {
+		results, err = run(ctx, o)
+	}
+
+	if results != nil {
+		results.Stats.Candidates = len(results.Folders)
+		results.Stats.Errors = len(results.Errors)
+		results.Stats.Duration = time.Since(start)
+	}
+	return results, err
+}
+
+func (o *Options) progress(format string, args ...interface{}) {
+	if o.Progress != nil {
+		o.Progress(fmt.Sprintf(format, args...))
+	}
+}
+
+func (o *Options) progressDone() {
+	if o.Progress != nil {
+		o.Progress("")
+	}
+}
+
+func (o *Options) warn(err error) {
+	if o.Warn != nil {
+		o.Warn(err)
+	}
+}
+
+func run(ctx context.Context, o *Options) (*Result, error) {
+	if o.UseIndex {
+		return runIndex(o)
+	}
+
+	results := newResults()
+	defer o.countEvents(results)()
+	walkCtx, cancel := ctx, context.CancelFunc(func() {})
+	if o.MaxDuration > 0 {
+		walkCtx, cancel = context.WithTimeout(ctx, o.MaxDuration)
+	}
+	defer cancel()
+
+	var candidates []*Folder
+	var err error
+	if o.Paths != nil {
+		candidates, err = pathCandidates(walkCtx, o, results)
+	} else {
+		candidates, err = discover(walkCtx, o, results)
+	}
+	if err != nil && walkCtx.Err() != nil && ctx.Err() == nil {
+		// Out of time, size what was found so far.
+		results.Partial, err = true, nil
+	}
+	if err != nil {
+		return results.stopped(ctx, o, err)
+	}
+
+	fsys := o.files()
+	size := func(ctx context.Context, p string) (usage, error) {
+		return folderSize(ctx, fsys, p, o.DiskUsage)
+	}
+	var cache *sizeCache
+	if o.SizeCache && o.real() {
+		if p, err := sizeCachePath(); err == nil {
+			cache = loadSizeCache(p, o.DiskUsage)
+			size = cache.size
+		}
+	}
+
+	var sized []*Folder
+	err = sizeCandidates(ctx, o, candidates, size, func(f *Folder) { sized = append(sized, f) }, func(f *Folder, err error) {
+		results.addError(o, f, err)
+	})
+	results.filterAll(o, sized)
+	if err != nil {
+		if cache != nil {
+			_ = cache.save()
+		}
+		return results.stopped(ctx, o, err)
+	}
+
+	if cache != nil {
+		if err := cache.save(); err != nil {
+			o.warn(fmt.Errorf("could not save size cache: %w", err))
+		}
+	}
+
+	results.finish(o)
+	return results, nil
+}
+
+// runIndex is run using the index kept by -watch instead of scanning.
+func runIndex(o *Options) (*Result, error) {
+	candidates, err := indexCandidates(o)
+	if err != nil {
+		return nil, err
+	}
+
+	results := newResults()
+	results.filterAll(o, candidates)
+	results.finish(o)
+	return results, nil
+}
+
+// filterAll groups the folders of workspace packages with the folder in their
+// workspace root, unless that is turned off or the folders were listed in
+// o.Paths, then filters them.
+func (r *Result) filterAll(o *Options, folders []*Folder) {
+	if !o.SplitWorkspaces && o.Paths == nil && !o.Histogram && !o.CachesOnly {
+		folders = groupWorkspaces(o, folders)
+	}
+	found := r.filter(o)
+	for _, f := range folders {
+		found(f)
+	}
+}
+
+// filter returns a function that adds a sized candidate to the results,
+// unless it is too small, or sets it aside if its project has unsaved work or
+// it is too large to delete without review.
+func (r *Result) filter(o *Options) func(*Folder) {
+	return func(f *Folder) {
+		if o.Histogram {
+			r.addDiscovered(f)
+			return
+		}
+
+		if !o.ignoreThresholds() && f.SizeBytes < o.minSize(f) {
+			o.skipped(f, SkipTooSmall)
+			return
+		}
+
+		if o.SkipDirty && o.real() && isGitRepo(f.Project) && hasUnsavedWork(f.Project, f.Path) {
+			o.skipped(f, SkipUnsaved)
+			r.addDirty(f)
+			return
+		}
+
+		if o.MaxSize > 0 && f.SizeBytes > o.MaxSize {
+			o.skipped(f, SkipNeedReview)
+			r.addReview(f)
+			return
+		}
+
+		r.add(f)
+	}
+}
+
+// stopped returns the folders found so far along with err if the scan was
+// stopped by ctx being cancelled, otherwise just err.
+func (r *Result) stopped(ctx context.Context, o *Options, err error) (*Result, error) {
+	if ctx.Err() == nil {
+		return nil, err
+	}
+
+	r.finish(o)
+	return r, err
+}
+
+// finish applies the rules that pick from all the folders found, then sorts
+// and limits them.
+func (r *Result) finish(o *Options) {
+	if o.KeepRecent > 0 {
+		r.dropping(o, SkipKeepRecent, func() { r.dropRecent(o.KeepRecent) })
+	}
+	if o.FreeGoal > 0 {
+		r.dropping(o, SkipFreeGoal, func() { r.planFree(o.FreeGoal) })
+	}
+	if o.SortBy == SortScore && !o.Histogram {
+		for _, folders := range [][]*Folder{r.Folders, r.Review, r.Dirty} {
+			for _, f := range folders {
+				score(o, f)
+			}
+		}
+	}
+	r.sort(o.SortBy, o.Reverse)
+	sort.Slice(r.Errors, func(i, j int) bool {
+		return r.Errors[i].Path < r.Errors[j].Path
+	})
+	if !o.ignoreLimit() {
+		r.dropping(o, SkipOverLimit, func() { r.truncate(o.Limit) })
+	}
+}
+
+// dropping runs drop, which removes some of r's folders, and reports each it
+// removed as skipped for reason.
+func (r *Result) dropping(o *Options, reason string, drop func()) {
+	before := make([]*Folder, len(r.Folders))
+	copy(before, r.Folders)
+	drop()
+
+	kept := make(map[*Folder]bool, len(r.Folders))
+	for _, f := range r.Folders {
+		kept[f] = true
+	}
+	for _, f := range before {
+		if !kept[f] {
+			o.skipped(f, reason)
+		}
+	}
+}
+
+// discover walks from the start folders to find every target folder whose
+// project is old enough, without working out any sizes. Folders that can't be
+// read are added to results' errors and skipped; only a start folder given in
+// o not being readable stops the walk. A folder reached from more than one
+// start folder is only included once.
+func discover(ctx context.Context, o *Options, results *Result) ([]*Folder, error) {
+	var mu sync.Mutex
+	var candidates []*Folder
+	seen := make(map[string]bool)
+
+	defer o.progressDone()
+
+	roots := startDirs(o)
+	remote := remoteSkipper(o, roots)
+	links := o.linkFollower()
+	for _, root := range roots {
+		err := discoverFrom(ctx, o, root, results, remote, links, func(f *Folder) int {
+			mu.Lock()
+			defer mu.Unlock()
+			if f != nil && !seen[f.Path] {
+				seen[f.Path] = true
+				candidates = append(candidates, f)
+			}
+			return len(candidates)
+		})
+		if err != nil {
+			return candidates, err
+		}
+	}
+
+	return candidates, nil
+}
+
+// startDirs returns the folders a scan starts from: o.FromDir and
+// o.ExtraDirs, or with o.PerUser each user's home folder, or with o.AllDrives
+// every fixed drive, and with o.IncludeWSL
+// the home folders of each WSL distribution. AllDrives and IncludeWSL only
+// apply on Windows.
+func startDirs(o *Options) []string {
+	dirs := append([]string{o.FromDir}, o.ExtraDirs...)
+	if o.PerUser && o.real() {
+		if homes, err := UserHomes(); err == nil && len(homes) > 0 {
+			dirs = homes
+		}
+	}
+	if o.AllDrives && o.real() {
+		if drives := fixedDrives(); len(drives) > 0 {
+			dirs = drives
+		}
+	}
+	if o.IncludeWSL && o.real() {
+		dirs = append(dirs, wslDirs()...)
+	}
+	return dirs
+}
+
+// isGivenDir reports whether dir is FromDir or one of ExtraDirs.
+func (o *Options) isGivenDir(dir string) bool {
+	if dir == o.FromDir {
+		return true
+	}
+	for _, d := range o.ExtraDirs {
+		if dir == d {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverFrom walks from root for discover, passing each accepted candidate
+// to found, which returns how many have been found. found is given nil to
+// just return the count. Only a start folder given in o not being readable
+// is an error; others that can't be read are added to results' errors.
+func discoverFrom(ctx context.Context, o *Options, root string, results *Result, remote func(string, fs.DirEntry) bool, links *linkFollower, found func(*Folder) int) error {
+	fsys := o.files()
+	onDevice := sameDevice(fsys, o, root)
+	return walkDirParallel(fsys, root, o.Workers, links, func(path string, d fs.DirEntry, err error) error {
+		if d == nil && !o.isGivenDir(root) {
+			results.addError(o, &Folder{Path: path}, err)
+			return nil
+		}
+		if d == nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			// Scanning the whole filesystem as anyone but root always meets
+			// other users' folders, which couldn't be deleted anyway.
+			if !(errors.Is(err, fs.ErrPermission) && isRoot(root)) {
+				results.addError(o, &Folder{Path: path}, err)
+			}
+			return fs.SkipDir
+		}
+
+		if !d.IsDir() || isLink(d) {
+			return nil
+		}
+
+		o.progress("Scanning, %d found: %s", found(nil), path)
+
+		reason := skipReason(o, root, path, d)
+		if reason == "" && o.tooDeep(root, path) && !leadsToTarget(o.Targets, d.Name()) {
+			if _, ok := matchTarget(fsys, o.Targets, path); !ok {
+				reason = SkipTooDeep
+			}
+		}
+		if reason == "" && !onDevice(d) {
+			reason = SkipOtherFS
+		}
+		if reason == "" && remote(path, d) {
+			reason = SkipRemote
+		}
+		o.visited(path, reason)
+		if reason != "" {
+			return fs.SkipDir
+		}
+
+		project, ok := matchTarget(fsys, o.Targets, path)
+		if !ok {
+			return nil
+		}
+		results.sawTarget()
+
+		folder := &Folder{
+			Path:     path,
+			Project:  project,
+			Artifact: isArtifact(o.Targets, path),
+		}
+
+		accepted, err := acceptCandidate(o, folder)
+		if err != nil {
+			results.addError(o, folder, err)
+			return fs.SkipDir
+		}
+
+		if accepted {
+			for _, f := range o.onlyCaches(fsys, folder) {
+				found(f)
+			}
+		}
+		return fs.SkipDir
+	})
+}
+
+// tooDeep reports whether path is more than o.MaxDepth folders below root.
+func (o *Options) tooDeep(root, path string) bool {
+	if o.MaxDepth <= 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return strings.Count(rel, string(filepath.Separator))+1 > o.MaxDepth
+}
+
+// linkFollower returns what a walk needs to follow links to folders, or nil
+// unless o.FollowSymlinks is set.
+func (o *Options) linkFollower() *linkFollower {
+	if !o.FollowSymlinks {
+		return nil
+	}
+	return newLinkFollower()
+}
+
+// isRoot reports whether dir is the root of a filesystem or drive.
+func isRoot(dir string) bool {
+	return filepath.Dir(dir) == dir
+}
+
+// sameDevice returns a function reporting whether a folder is on the same
+// filesystem as root, if o.OneFileSystem is set. Otherwise, or where
+// filesystems can't be told apart, every folder is.
+func sameDevice(fsys fileSystem, o *Options, root string) func(fs.DirEntry) bool {
+	all := func(fs.DirEntry) bool { return true }
+	if !o.OneFileSystem {
+		return all
+	}
+
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		return all
+	}
+	start, ok := device(info)
+	if !ok {
+		return all
+	}
+
+	return func(d fs.DirEntry) bool {
+		info, err := d.Info()
+		if err != nil {
+			return true
+		}
+		dev, ok := device(info)
+		return !ok || dev == start
+	}
+}
+
+// skipFolder reports whether the folder at path, and everything below it,
+// should not be scanned because it is hidden or excluded. root, the folder the
+// scan started from, is never hidden.
+func skipFolder(o *Options, root, path string, d fs.DirEntry) bool {
+	return skipReason(o, root, path, d) != ""
+}
+
+// skipReason returns why skipFolder skips the folder at path, e.g.
+// SkipExcluded, or "" if it doesn't.
+func skipReason(o *Options, root, path string, d fs.DirEntry) string {
+	if isPending(d.Name()) {
+		return SkipPendingDelete
+	}
+
+	if o.SkipHidden && path != root && isHidden(d.Name()) && !leadsToTarget(o.Targets, d.Name()) {
+		return SkipHiddenFolder
+	}
+
+	for _, excludePattern := range excludeFolders {
+		if excludePattern.MatchString(path) {
+			return SkipSystemFolder
+		}
+	}
+	if !o.IncludeLibrary {
+		for _, excludePattern := range libraryFolders {
+			if excludePattern.MatchString(path) {
+				return SkipSystemFolder
+			}
+		}
+	}
+
+	if isExcluded(o.Excludes, path) {
+		return SkipExcluded
+	}
+	return ""
+}
+
+// acceptCandidate reports whether a found target folder should go on to be
+// sized, checking the project isn't kept and is old enough. The project's
+// modified time is worked out and set on f unless it is already known.
+func acceptCandidate(o *Options, f *Folder) (bool, error) {
+	if o.Histogram {
+		o.emit(Event{Kind: FolderFound, Folder: f})
+		return true, nil
+	}
+
+	if isKept(o.files(), f.Project) {
+		o.skipped(f, SkipKept)
+		return false, nil
+	}
+
+	if o.OrphansOnly && !isOrphan(o.files(), f.Project) {
+		o.skipped(f, SkipNotOrphan)
+		return false, nil
+	}
+
+	if f.ModTime.IsZero() {
+		modTime, err := projectModTime(o, f.Project, f.Path)
+		if err != nil {
+			return false, err
+		}
+		f.ModTime = modTime
+	}
+	f.ModDaysAgo = daysSince(f.ModTime)
+
+	if planned, ok := o.PlannedModTimes[f.Path]; ok && !planned.IsZero() && f.ModTime.After(planned) {
+		return false, errChangedSincePlan
+	}
+
+	if !o.ignoreThresholds() && time.Since(f.ModTime) < o.olderThan(f) {
+		o.skipped(f, SkipTooRecent)
+		return false, nil
+	}
+
+	if f.Artifact && o.real() && isTracked(f.Project, f.Path) {
+		o.skipped(f, SkipTracked)
+		return false, nil
+	}
+
+	f.PackageManager = detectPackageManager(o.files(), f.Project)
+	f.PackageCount = countPackages(o.files(), f.Path)
+	o.emit(Event{Kind: FolderFound, Folder: f})
+	return true, nil
+}
+
+// sizeCandidates works out the size of each candidate with size using
+// o.Workers goroutines, then passes it to found. found is only ever called
+// from one goroutine at a time. A candidate that can't be sized is passed to
+// failed instead, which may be called from any of the goroutines. Sizing
+// stops early if ctx is cancelled.
+func sizeCandidates(ctx context.Context, o *Options, candidates []*Folder, size func(context.Context, string) (usage, error), found func(*Folder), failed func(*Folder, error)) error {
+	jobs := make(chan *Folder)
+	sized := make(chan *Folder)
+
+	workers := o.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				u, err := size(ctx, f.Path)
+				if err != nil {
+					if ctx.Err() == nil {
+						failed(f, err)
+					}
+					continue
+				}
+				f.setUsage(u)
+				sized <- f
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for _, f := range candidates {
+			select {
+			case jobs <- f:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(sized)
+	}()
+
+	done := 0
+	var total int64
+	for f := range sized {
+		done++
+		total += f.SizeBytes
+		o.progress("Sizing %d/%d folders, %s so far", done, len(candidates), FormatSize(total))
+		o.emit(Event{Kind: SizeComputed, Folder: f})
+		found(f)
+	}
+	o.progressDone()
+
+	return ctx.Err()
+}
+
+// isOrphan reports whether project has no package.json, meaning its
+// node_modules was left behind by a project that has been deleted or moved.
+func isOrphan(fsys fileSystem, project string) bool {
+	_, err := fsys.Stat(filepath.Join(project, PackageJSON))
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+func latestModifiedFile(fsys fileSystem, p string, targets []Target) (time.Time, error) {
+	lastModified := time.Time{}
+	err := walkDir(fsys, p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if _, ok := matchTarget(fsys, targets, path); ok {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		modTime := info.ModTime()
+		if modTime.After(lastModified) {
+			lastModified = modTime
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return lastModified, nil
+}
+
+func daysSince(t time.Time) int {
+	return int(time.Now().Unix()-t.Unix()) / 60 / 60 / 24
+}
+
+// usage is the space taken up by a folder.
+type usage struct {
+	SizeBytes        int64 `json:"sizeBytes"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+// fileID identifies a file regardless of which of its hard links it is found
+// through.
+type fileID struct {
+	dev, ino uint64
+}
+
+type linkedFile struct {
+	links, seen uint64
+	size        int64
+}
+
+// folderSize adds up the size of every file below p, stopping early if ctx is
+// cancelled. With allocated, each file's space on disk is used instead of its
+// size where the platform reports it. A file with more than one hard link only
+// counts as reclaimable if all of its links are below p, as otherwise
+// deleting p won't free it.
+func folderSize(ctx context.Context, fsys fileSystem, p string, allocated bool) (usage, error) {
+	var u usage
+	linked := make(map[fileID]*linkedFile)
+	err := walkDir(fsys, p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		size := info.Size()
+		if allocated {
+			if n, ok := allocatedSize(path, info); ok {
+				size = n
+			}
+		}
+
+		u.SizeBytes += size
+		if id, links, ok := hardLinks(info); ok && links > 1 {
+			l := linked[id]
+			if l == nil {
+				l = &linkedFile{links: links, size: size}
+				linked[id] = l
+			}
+			l.seen++
+			return nil
+		}
+
+		u.ReclaimableBytes += size
+		return nil
+	})
+
+	if err != nil {
+		return usage{}, err
+	}
+
+	for _, l := range linked {
+		if l.seen >= l.links {
+			u.ReclaimableBytes += l.size
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,310 @@
+package cleaner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// projectFS returns a filesystem of projects, each with a package.json and a
+// node_modules folder holding size bytes, last modified age ago.
+func projectFS(projects map[string]struct {
+	size int
+	age  time.Duration
+}) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, p := range projects {
+		mod := time.Now().Add(-p.age)
+		fsys[name+"/package.json"] = &fstest.MapFile{Data: []byte("{}"), ModTime: mod}
+		fsys[name+"/node_modules/pkg/index.js"] = &fstest.MapFile{Data: make([]byte, p.size), ModTime: mod}
+	}
+	return fsys
+}
+
+func TestScan(t *testing.T) {
+	type project = struct {
+		size int
+		age  time.Duration
+	}
+	projects := map[string]project{
+		"old":          {size: 2000, age: 30 * Day},
+		"recent":       {size: 2000, age: time.Hour},
+		"small":        {size: 10, age: 30 * Day},
+		"work/archive": {size: 3000, age: 60 * Day},
+		".hidden/app":  {size: 2000, age: 30 * Day},
+	}
+
+	tests := []struct {
+		name        string
+		setup       func(o *Options)
+		want        []string
+		wantSkipped map[string]int
+	}{
+		{
+			name: "old and large enough",
+			want: []string{"work/archive/node_modules", "old/node_modules"},
+			wantSkipped: map[string]int{
+				SkipTooRecent:    1,
+				SkipTooSmall:     1,
+				SkipHiddenFolder: 1,
+			},
+		},
+		{
+			name: "excluded",
+			setup: func(o *Options) {
+				o.Excludes = []*regexp.Regexp{mustCompileExclude(t, "work")}
+			},
+			want: []string{"old/node_modules"},
+		},
+		{
+			name: "from a folder below the root",
+			setup: func(o *Options) {
+				o.FromDir = "/work"
+			},
+			want: []string{"work/archive/node_modules"},
+		},
+		{
+			name: "hidden folders included",
+			setup: func(o *Options) {
+				o.SkipHidden = false
+			},
+			want: []string{"work/archive/node_modules", ".hidden/app/node_modules", "old/node_modules"},
+		},
+		{
+			name: "limited",
+			setup: func(o *Options) {
+				o.Limit = 1
+			},
+			want: []string{"work/archive/node_modules"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := DefaultOptions()
+			o.FS = projectFS(projects)
+			o.FromDir = "/"
+			o.MinSize = 1000
+			o.SortBy = SortSize
+			if tt.setup != nil {
+				tt.setup(&o)
+			}
+
+			results, err := NewScanner(o).Scan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, f := range results.Folders {
+				got = append(got, f.Path)
+			}
+			want := make([]string, len(tt.want))
+			for i, p := range tt.want {
+				want[i] = filepath.FromSlash("/" + p)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("folders = %q, want %q", got, want)
+			}
+
+			for reason, n := range tt.wantSkipped {
+				if results.Stats.Skipped[reason] != n {
+					t.Errorf("skipped %q = %d, want %d", reason, results.Stats.Skipped[reason], n)
+				}
+			}
+		})
+	}
+}
+
+func TestScanSizes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/package.json":             {Data: []byte("{}"), ModTime: time.Now().Add(-30 * Day)},
+		"app/node_modules/a/index.js":  {Data: make([]byte, 1500)},
+		"app/node_modules/b/index.js":  {Data: make([]byte, 500)},
+		"app/node_modules/b/README.md": {Data: make([]byte, 250)},
+	}
+
+	o := DefaultOptions()
+	o.FS = fsys
+	o.FromDir = "/"
+	o.MinSize = 0
+	results, err := NewScanner(o).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Folders) != 1 {
+		t.Fatalf("got %d folders, want 1", len(results.Folders))
+	}
+
+	f := results.Folders[0]
+	if f.SizeBytes != 2250 {
+		t.Errorf("size = %d, want 2250", f.SizeBytes)
+	}
+	if want := filepath.FromSlash("/app"); f.Project != want {
+		t.Errorf("project = %q, want %q", f.Project, want)
+	}
+	if results.TotalSize != f.SizeBytes {
+		t.Errorf("total = %d, want %d", results.TotalSize, f.SizeBytes)
+	}
+}
+
+func TestScanPaths(t *testing.T) {
+	fsys := projectFS(map[string]struct {
+		size int
+		age  time.Duration
+	}{
+		"a": {size: 100, age: time.Hour},
+		"b": {size: 100, age: time.Hour},
+	})
+
+	o := DefaultOptions()
+	o.FS = fsys
+	o.Paths = []string{"/a", "/b/node_modules", "/missing"}
+	o.Exact = true
+	results, err := NewScanner(o).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, f := range results.Folders {
+		got = append(got, f.Path)
+	}
+	sort.Strings(got)
+	want := []string{filepath.FromSlash("/a/node_modules"), filepath.FromSlash("/b/node_modules")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("folders = %q, want %q", got, want)
+	}
+	if len(results.Errors) != 1 || results.Errors[0].Path != filepath.FromSlash("/missing") {
+		t.Errorf("errors = %+v, want one for /missing", results.Errors)
+	}
+}
+
+func TestScanWorkspaces(t *testing.T) {
+	mod := time.Now().Add(-30 * Day)
+	file := func(data string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(data), ModTime: mod}
+	}
+	fsys := fstest.MapFS{
+		"repo/package.json":                    file(`{"workspaces": ["packages/*"]}`),
+		"repo/.yarn/cache/a.zip":               file("aaaa"),
+		"repo/packages/app/package.json":       file("{}"),
+		"repo/packages/app/.yarn/cache/b.zip":  file("bb"),
+		"repo/packages/site/package.json":      file("{}"),
+		"repo/packages/site/.yarn/cache/c.zip": file("c"),
+	}
+
+	targets, err := BuildTargets(nil, []string{".yarn/cache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := DefaultOptions()
+	o.FS = fsys
+	o.FromDir = "/"
+	o.MinSize = 0
+	o.Targets = targets
+	results, err := NewScanner(o).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Folders) != 1 {
+		t.Fatalf("got %d folders, want the workspace root's alone", len(results.Folders))
+	}
+
+	f := results.Folders[0]
+	if want := filepath.FromSlash("/repo/.yarn/cache"); f.Path != want {
+		t.Errorf("path = %q, want %q", f.Path, want)
+	}
+	members := append([]string(nil), f.Members...)
+	sort.Strings(members)
+	want := []string{filepath.FromSlash("/repo/packages/app/.yarn/cache"), filepath.FromSlash("/repo/packages/site/.yarn/cache")}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("members = %q, want %q", members, want)
+	}
+	if f.SizeBytes != 7 {
+		t.Errorf("size = %d, want 7", f.SizeBytes)
+	}
+}
+
+func mustCompileExclude(t *testing.T, pattern string) *regexp.Regexp {
+	t.Helper()
+	re, err := CompileExclude(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return re
+}
+
+func TestScanFreeGoalHardLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard links are only detected on Linux and macOS")
+	}
+
+	// pnpm is the largest, but its files are hard linked from the store, so
+	// deleting it frees nothing.
+	root := t.TempDir()
+	store := filepath.Join(root, "store", "big.js")
+	for name, size := range map[string]int{"store/big.js": 3000, "a/node_modules/a.js": 2000, "b/node_modules/b.js": 1500} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "pnpm", NodeModules), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(store, filepath.Join(root, "pnpm", NodeModules, "big.js")); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-30 * Day)
+	for _, name := range []string{"a", "b", "pnpm"} {
+		p := filepath.Join(root, name, "package.json")
+		if err := os.WriteFile(p, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		goal int64
+		want []string
+	}{
+		{goal: 1000, want: []string{"a"}},
+		{goal: 3000, want: []string{"a", "b"}},
+		{goal: 10000, want: []string{"a", "b", "pnpm"}},
+	}
+	for _, tt := range tests {
+		t.Run(FormatSize(tt.goal), func(t *testing.T) {
+			o := DefaultOptions()
+			o.FromDir = root
+			o.MinSize = 0
+			o.SizeCache = false
+			o.FreeGoal = tt.goal
+			results, err := NewScanner(o).Scan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, f := range results.Folders {
+				got = append(got, filepath.Base(f.Project))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planned %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package cleaner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortScore sorts folders by their reclaim score, highest first.
+const SortScore = "score"
+
+// ScoreWeights are how much each part of a folder's reclaim score counts
+// towards it. A weight of 0 leaves that part out.
+type ScoreWeights struct {
+	// Size is for the folder's size, up to scoreFullSize.
+	Size float64
+	// Age is for how long since the folder itself was modified, as happens
+	// when packages are installed.
+	Age float64
+	// Activity is for how long since a file in the project was modified.
+	Activity float64
+	// Git is for how long since the project's last commit. A project that
+	// isn't a git repo uses its activity instead.
+	Git float64
+}
+
+// DefaultScoreWeights count how long a project has been left alone for more
+// than its size, so the largest project still being worked on doesn't come
+// first.
+var DefaultScoreWeights = ScoreWeights{Size: 1, Age: 1, Activity: 2, Git: 2}
+
+const (
+	// scoreFullSize and scoreFullAge are the size and age at which each part
+	// of the score is at its highest.
+	scoreFullSize = GB
+	scoreFullAge  = 365 * Day
+)
+
+// ParseScoreWeights parses weights such as "size=1,age=1,activity=2,git=2".
+// Parts left out keep their default weight.
+func ParseScoreWeights(s string) (ScoreWeights, error) {
+	w := DefaultScoreWeights
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || weight < 0 {
+			return w, fmt.Errorf("invalid score weight %q, expected e.g. size=1", part)
+		}
+
+		switch strings.TrimSpace(name) {
+		case "size":
+			w.Size = weight
+		case "age":
+			w.Age = weight
+		case "activity":
+			w.Activity = weight
+		case "git":
+			w.Git = weight
+		default:
+			return w, fmt.Errorf("unknown score weight %q, expected size, age, activity or git", name)
+		}
+	}
+	return w, nil
+}
+
+func (w ScoreWeights) String() string {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	return fmt.Sprintf("size=%s,age=%s,activity=%s,git=%s", format(w.Size), format(w.Age), format(w.Activity), format(w.Git))
+}
+
+// score sets f's reclaim score, from 0 to 100: the weighted average of its
+// size and how long since the folder, its project's files and its project's
+// last commit were modified, each scaled from 0 up to scoreFullSize or
+// scoreFullAge.
+func score(o *Options, f *Folder) {
+	w := o.ScoreWeights
+	total := w.Size + w.Age + w.Activity + w.Git
+	if total == 0 {
+		f.Score = 0
+		return
+	}
+
+	scaled := func(v, full float64) float64 {
+		if v < 0 {
+			return 0
+		}
+		if v > full {
+			return 1
+		}
+		return v / full
+	}
+	ageOf := func(t time.Time) float64 {
+		if t.IsZero() {
+			return 0
+		}
+		return scaled(float64(time.Since(t)), float64(scoreFullAge))
+	}
+
+	activity := f.ModTime
+	if o.AgeSource != AgeSourceProject {
+		if t, err := latestModifiedFile(o.files(), f.Project, o.Targets); err == nil {
+			activity = t
+		}
+	}
+
+	age := activity
+	if w.Age > 0 {
+		if info, err := o.files().Stat(f.Path); err == nil {
+			age = info.ModTime()
+		}
+	}
+
+	commit := activity
+	if w.Git > 0 && o.real() && isGitRepo(f.Project) {
+		if t, err := lastCommitTime(f.Project); err == nil {
+			commit = t
+		}
+	}
+
+	sum := w.Size*scaled(float64(f.SizeBytes), float64(scoreFullSize)) +
+		w.Age*ageOf(age) +
+		w.Activity*ageOf(activity) +
+		w.Git*ageOf(commit)
+	f.Score = 100 * sum / total
+}
@@ -0,0 +1,60 @@
+package cleaner
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	KB int64 = 1024
+	MB       = 1024 * KB
+	GB       = 1024 * MB
+	TB       = 1024 * GB
+)
+
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", TB},
+	{"GB", GB},
+	{"MB", MB},
+	{"KB", KB},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as 500MB, 1.5GB or 200 into bytes. Units are
+// powers of 1024 and case insensitive, and a number on its own is in MB.
+func ParseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.Replace(v, "IB", "B", 1)
+
+	unit := MB
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, unit = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.size
+			break
+		}
+	}
+
+	// "inf" and "nan" parse as numbers but aren't sizes.
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) || n*float64(unit) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int64(n * float64(unit)), nil
+}
+
+// FormatSize formats b in the largest unit it is at least one of, with one
+// decimal place, e.g. 1.5GB or 820.3MB.
+func FormatSize(b int64) string {
+	for _, u := range sizeUnits {
+		if b >= u.size && u.size > 1 {
+			return strconv.FormatFloat(float64(b)/float64(u.size), 'f', 1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(b, 10) + "B"
+}
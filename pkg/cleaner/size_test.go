@@ -0,0 +1,35 @@
+package cleaner
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "50", want: 50 * MB},
+		{in: "50MB", want: 50 * MB},
+		{in: "1.5GB", want: 3 * GB / 2},
+		{in: "512kb", want: 512 * KB},
+		{in: "2GiB", want: 2 * GB},
+		{in: "100B", want: 100},
+		{in: "-1MB", wantErr: true},
+		{in: "inf", wantErr: true},
+		{in: "infGB", wantErr: true},
+		{in: "nan", wantErr: true},
+		{in: "NaN MB", wantErr: true},
+		{in: "1e30TB", wantErr: true},
+		{in: "big", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) err = %v, want error %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
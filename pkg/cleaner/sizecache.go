@@ -0,0 +1,124 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// sizeCacheFile is versioned as entries in the original sizes.json have no
+// reclaimable size.
+const sizeCacheFile = "sizes-v2.json"
+
+type sizeCacheEntry struct {
+	ModTime   int64 `json:"modTime"`
+	Allocated bool  `json:"allocated,omitempty"`
+	usage
+}
+
+// sizeCache remembers folder sizes between runs, keyed by path. An entry is
+// only used while the folder's own modified time is unchanged, which is the
+// case until packages are added or removed, and if it was sized the same way,
+// by allocated space or not.
+type sizeCache struct {
+	mu        sync.Mutex
+	path      string
+	allocated bool
+	entries   map[string]sizeCacheEntry
+	dirty     bool
+}
+
+func sizeCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "npm-cleaner", sizeCacheFile), nil
+}
+
+// loadSizeCache reads the cache at p, for sizes by allocated space if
+// allocated is set. A missing or unreadable cache is treated as empty.
+func loadSizeCache(p string, allocated bool) *sizeCache {
+	c := &sizeCache{
+		path:      p,
+		allocated: allocated,
+		entries:   make(map[string]sizeCacheEntry),
+	}
+
+	data, err := os.ReadFile(p)
+	if err == nil {
+		_ = json.Unmarshal(data, &c.entries)
+	}
+	return c
+}
+
+// size returns the size of the folder at p, from the cache if its modified
+// time hasn't changed, otherwise by walking it with folderSize.
+func (c *sizeCache) size(ctx context.Context, p string) (usage, error) {
+	info, err := os.Stat(longPath(p))
+	if err != nil {
+		return usage{}, err
+	}
+	modTime := info.ModTime().UnixNano()
+
+	c.mu.Lock()
+	entry, ok := c.entries[p]
+	c.mu.Unlock()
+	if ok && entry.ModTime == modTime && entry.Allocated == c.allocated {
+		return entry.usage, nil
+	}
+
+	u, err := folderSize(ctx, osFS{}, p, c.allocated)
+	if err != nil {
+		return usage{}, err
+	}
+	// What can be reclaimed from a folder sharing hard linked files changes
+	// with the other links, without the folder itself changing.
+	if u.ReclaimableBytes != u.SizeBytes {
+		return u, nil
+	}
+
+	c.mu.Lock()
+	c.entries[p] = sizeCacheEntry{ModTime: modTime, Allocated: c.allocated, usage: u}
+	c.dirty = true
+	c.mu.Unlock()
+	return u, nil
+}
+
+func (c *sizeCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	// Drop folders that no longer exist so the cache doesn't grow forever.
+	for p := range c.entries {
+		if _, err := os.Stat(longPath(p)); errors.Is(err, fs.ErrNotExist) {
+			delete(c.entries, p)
+		}
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
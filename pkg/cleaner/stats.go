@@ -0,0 +1,83 @@
+package cleaner
+
+import (
+	"sort"
+	"time"
+)
+
+// ScanStats count what a scan did, so a scan that found nothing can be told
+// apart from one that hardly looked.
+type ScanStats struct {
+	// FoldersVisited is how many folders the walk looked at, including those
+	// it didn't look inside.
+	FoldersVisited int
+	// TargetsSeen is how many target folders, such as node_modules, were
+	// found, before any were left out.
+	TargetsSeen int
+	// Candidates is how many folders ended up in Result.Folders.
+	Candidates int
+	// Skipped is how many target folders were left out, and folders not
+	// looked inside, for each reason, e.g. SkipTooRecent.
+	Skipped map[string]int
+	// Errors is how many folders couldn't be read.
+	Errors   int
+	Duration time.Duration
+}
+
+// SkipReasons returns the reasons in Skipped, most common first.
+func (s *ScanStats) SkipReasons() []string {
+	reasons := make([]string, 0, len(s.Skipped))
+	for reason := range s.Skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		a, b := reasons[i], reasons[j]
+		if s.Skipped[a] != s.Skipped[b] {
+			return s.Skipped[a] > s.Skipped[b]
+		}
+		return a < b
+	})
+	return reasons
+}
+
+// TotalSkipped is how many folders were skipped for any reason.
+func (s *ScanStats) TotalSkipped() int {
+	total := 0
+	for _, n := range s.Skipped {
+		total += n
+	}
+	return total
+}
+
+// sawTarget counts a target folder found, before any checks.
+func (r *Result) sawTarget() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Stats.TargetsSeen++
+}
+
+// countEvents counts the folders visited and skipped in r's stats as o
+// reports them, until the returned function is called.
+func (o *Options) countEvents(r *Result) (stop func()) {
+	onEvent := o.OnEvent
+	o.OnEvent = func(e Event) {
+		switch e.Kind {
+		case FolderVisited:
+			r.mu.Lock()
+			r.Stats.FoldersVisited++
+			if e.Reason != "" {
+				r.Stats.Skipped[e.Reason]++
+			}
+			r.mu.Unlock()
+		case FolderSkipped:
+			r.mu.Lock()
+			r.Stats.Skipped[e.Reason]++
+			r.mu.Unlock()
+		}
+
+		if onEvent != nil {
+			onEvent(e)
+		}
+	}
+	return func() { o.OnEvent = onEvent }
+}
@@ -0,0 +1,153 @@
+package cleaner
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Target is a folder name to look for. If markers are given at least one of
+// them, a glob relative to the project folder, must match for the folder to
+// be a candidate, e.g. a rust target folder needs a Cargo.toml beside it.
+type Target struct {
+	name    string
+	markers []string
+	// artifact is set for build output, such as dist, which has its own
+	// thresholds and is never looked for inside node_modules.
+	artifact bool
+}
+
+var presets = map[string][]Target{
+	"npm": {
+		{name: NodeModules},
+	},
+	"rust": {
+		{name: "target", markers: []string{"Cargo.toml"}},
+	},
+	"python": {
+		{name: ".venv", markers: []string{"pyproject.toml", "requirements*.txt", "setup.py", "Pipfile", ".venv/pyvenv.cfg"}},
+		{name: "__pycache__", markers: []string{"*.py"}},
+	},
+	"java": {
+		{name: "target", markers: []string{"pom.xml"}},
+		{name: "build", markers: []string{"build.gradle", "build.gradle.kts"}},
+		{name: ".gradle", markers: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}},
+	},
+	ArtifactsPreset: artifactTargets(PackageJSON, "dist", "build", ".next", ".nuxt", ".turbo", ".parcel-cache", "coverage", "storybook-static"),
+}
+
+// ArtifactsPreset is the preset for the build output of JavaScript projects,
+// added to the others by -build-artifacts.
+const ArtifactsPreset = "js-build"
+
+// artifactTargets returns build output folders with the names given, in
+// projects with marker.
+func artifactTargets(marker string, names ...string) []Target {
+	targets := make([]Target, len(names))
+	for i, name := range names {
+		targets[i] = Target{name: name, markers: []string{marker}, artifact: true}
+	}
+	return targets
+}
+
+const DefaultPreset = "npm"
+
+// PresetNames lists the preset names for help text.
+func PresetNames() string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// BuildTargets combines the chosen presets with any extra folder names. If
+// neither is given the npm preset is used.
+func BuildTargets(presetNames, names []string) ([]Target, error) {
+	if len(presetNames) == 0 && len(names) == 0 {
+		presetNames = []string{DefaultPreset}
+	}
+
+	var targets []Target
+	for _, p := range presetNames {
+		preset, ok := presets[p]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q", p)
+		}
+		targets = append(targets, preset...)
+	}
+	for _, name := range names {
+		targets = append(targets, Target{name: name})
+	}
+
+	return targets, nil
+}
+
+// matchTarget reports whether path is one of the target folders and if so
+// returns the project folder it belongs to. Targets may span more than one
+// folder, e.g. .yarn/cache belongs to the folder containing .yarn. Targets
+// with markers only match if one of the markers exists in the project.
+func matchTarget(fsys fileSystem, targets []Target, path string) (string, bool) {
+	slashed := filepath.ToSlash(path)
+	for _, t := range targets {
+		suffix := "/" + strings.Trim(filepath.ToSlash(t.name), "/")
+		if !strings.HasSuffix(slashed, suffix) {
+			continue
+		}
+
+		project := strings.TrimSuffix(slashed, suffix)
+		if project == "" || strings.HasSuffix(project, ":") {
+			project += "/"
+		}
+		project = filepath.FromSlash(project)
+		if t.artifact && inNodeModules(project) {
+			continue
+		}
+
+		if hasMarker(fsys, project, t.markers) {
+			return project, true
+		}
+	}
+
+	return "", false
+}
+
+// isArtifact reports whether path, a target folder, is build output rather
+// than installed packages.
+func isArtifact(targets []Target, path string) bool {
+	slashed := filepath.ToSlash(path)
+	for _, t := range targets {
+		if t.artifact && strings.HasSuffix(slashed, "/"+strings.Trim(filepath.ToSlash(t.name), "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMarker(fsys fileSystem, project string, markers []string) bool {
+	if len(markers) == 0 {
+		return true
+	}
+
+	for _, m := range markers {
+		matches, err := glob(fsys, filepath.Join(project, m))
+		if err == nil && len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsToTarget reports whether a folder named name is a target, or the first
+// part of a multi-folder one, so must be walked into even if it is hidden.
+func leadsToTarget(targets []Target, name string) bool {
+	for _, t := range targets {
+		parts := strings.Split(strings.Trim(filepath.ToSlash(t.name), "/"), "/")
+		if parts[0] == name {
+			return true
+		}
+	}
+	return false
+}
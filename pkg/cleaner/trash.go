@@ -0,0 +1,27 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// uniqueTrashName returns a name for base that does not already exist in
+// dir, adding a numeric suffix if needed, e.g. node_modules.2.
+func uniqueTrashName(dir, base string) (string, error) {
+	name := base
+	for i := 2; ; i++ {
+		_, err := os.Lstat(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if i > 10000 {
+			return "", fmt.Errorf("no free name for %s in %s", base, dir)
+		}
+		name = base + "." + strconv.Itoa(i)
+	}
+}
@@ -0,0 +1,23 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// moveToTrash moves p into ~/.Trash. Folders on other volumes can't be
+// renamed there and return an error.
+func moveToTrash(p string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	trash := filepath.Join(home, ".Trash")
+	name, err := uniqueTrashName(trash, filepath.Base(p))
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(p, filepath.Join(trash, name))
+}
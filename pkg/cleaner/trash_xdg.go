@@ -0,0 +1,108 @@
+//go:build !windows && !darwin
+
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// moveToTrash moves p to the freedesktop.org trash. The home trash is used
+// when p is on the same filesystem, otherwise the $topdir/.Trash-$uid trash
+// of the filesystem p is on.
+func moveToTrash(p string) error {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return err
+	}
+
+	homeTrash, err := homeTrashDir()
+	if err != nil {
+		return err
+	}
+
+	err = moveToTrashDir(abs, homeTrash)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	top, err := mountPoint(abs)
+	if err != nil {
+		return err
+	}
+
+	return moveToTrashDir(abs, filepath.Join(top, ".Trash-"+strconv.Itoa(os.Getuid())))
+}
+
+func homeTrashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+func moveToTrashDir(abs, trash string) error {
+	filesDir := filepath.Join(trash, "files")
+	infoDir := filepath.Join(trash, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	name, err := uniqueTrashName(filesDir, filepath.Base(abs))
+	if err != nil {
+		return err
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: abs}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	infoPath := filepath.Join(infoDir, name+".trashinfo")
+	if err := os.WriteFile(infoPath, []byte(info), 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(abs, filepath.Join(filesDir, name)); err != nil {
+		_ = os.Remove(infoPath)
+		return err
+	}
+
+	return nil
+}
+
+// mountPoint returns the top directory of the filesystem p is on.
+func mountPoint(p string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(p, &st); err != nil {
+		return "", err
+	}
+
+	dir := p
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+
+		var pst syscall.Stat_t
+		if err := syscall.Stat(parent, &pst); err != nil {
+			return "", err
+		}
+		if pst.Dev != st.Dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,37 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// sharedProfiles are folders alongside users' home folders that don't belong
+// to a user.
+var sharedProfiles = map[string]bool{
+	"All Users":    true,
+	"Default":      true,
+	"Default User": true,
+	"Public":       true,
+	"Shared":       true,
+	"lost+found":   true,
+}
+
+// UserHomes returns the home folder of each user of the machine: the folders
+// in /home on Linux, /Users on macOS or C:\Users on Windows, leaving out
+// shared and default profiles.
+func UserHomes() ([]string, error) {
+	base := usersDir()
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return nil, err
+	}
+
+	var homes []string
+	for _, e := range entries {
+		if !e.IsDir() || isHidden(e.Name()) || sharedProfiles[e.Name()] {
+			continue
+		}
+		homes = append(homes, filepath.Join(base, e.Name()))
+	}
+	return homes, nil
+}
@@ -0,0 +1,5 @@
+package cleaner
+
+func usersDir() string {
+	return "/Users"
+}
@@ -0,0 +1,7 @@
+//go:build !windows && !darwin
+
+package cleaner
+
+func usersDir() string {
+	return "/home"
+}
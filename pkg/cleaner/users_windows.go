@@ -0,0 +1,14 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func usersDir() string {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	return filepath.Join(drive+`\`, "Users")
+}
@@ -0,0 +1,113 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Volume is a filesystem holding folders to delete, with its free space
+// before and after deleting them, to check how much was really freed against
+// the folder sizes.
+type Volume struct {
+	// Path is where the filesystem is mounted, or the drive on Windows.
+	Path       string
+	FreeBefore int64
+	FreeAfter  int64
+	// Estimated is the space the folders deleted from the volume were expected
+	// to free.
+	Estimated int64
+	// Err is set if the free space couldn't be read, in which case Freed means
+	// nothing.
+	Err error
+
+	id string
+}
+
+// Freed is how much more free space the volume has after deleting.
+func (v *Volume) Freed() int64 {
+	return v.FreeAfter - v.FreeBefore
+}
+
+// Mismatched reports whether the space freed is far enough from the estimate
+// to be worth pointing out: more than 1MB and 10% either way. Sizes that
+// aren't DiskUsage, hard links to files outside the folders, files still held
+// open, failed deletions and anything else writing to the volume meanwhile all
+// make a difference.
+func (v *Volume) Mismatched() bool {
+	diff := v.Freed() - v.Estimated
+	if diff < 0 {
+		diff = -diff
+	}
+	return v.Err == nil && diff > MB && diff*10 > v.Estimated
+}
+
+// MeasureVolumes records the free space on each filesystem holding folders,
+// before they are deleted. Pass the result to FinishVolumes afterwards.
+func MeasureVolumes(folders []*Folder) []*Volume {
+	var volumes []*Volume
+	byID := make(map[string]*Volume)
+	for _, f := range folders {
+		id, root := volumeOf(filepath.Dir(f.Path))
+		if byID[id] != nil {
+			continue
+		}
+
+		v := &Volume{Path: root, id: id}
+		v.FreeBefore, v.Err = freeSpace(root)
+		byID[id] = v
+		volumes = append(volumes, v)
+	}
+	return volumes
+}
+
+// FinishVolumes records the free space on each of volumes again after
+// deleting, and what deleted estimated would be freed on each.
+func FinishVolumes(volumes []*Volume, deleted []DeleteResult) {
+	for _, v := range volumes {
+		if v.Err == nil {
+			v.FreeAfter, v.Err = freeSpace(v.Path)
+		}
+	}
+
+	for _, r := range deleted {
+		id, _ := volumeOf(filepath.Dir(r.Path))
+		for _, v := range volumes {
+			if v.id == id {
+				v.Estimated += r.BytesFreed
+			}
+		}
+	}
+}
+
+// volumeOf returns an identifier for the filesystem holding path, and where
+// it is mounted. Where the device isn't known, as on Windows, it is the drive.
+func volumeOf(path string) (string, string) {
+	drive := filepath.VolumeName(path) + string(filepath.Separator)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return drive, drive
+	}
+	dev, ok := device(info)
+	if !ok {
+		return drive, drive
+	}
+
+	root := path
+	for {
+		parent := filepath.Dir(root)
+		if parent == root {
+			break
+		}
+		info, err := os.Stat(parent)
+		if err != nil {
+			break
+		}
+		if d, _ := device(info); d != dev {
+			break
+		}
+		root = parent
+	}
+	return strconv.FormatUint(dev, 10), root
+}
@@ -0,0 +1,205 @@
+package cleaner
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sync"
+)
+
+// walkDirParallel walks the tree rooted at root like filepath.WalkDir, but
+// reads up to workers folders at once. fn may be called from several
+// goroutines at the same time, and entries are not visited in lexical order.
+// Returning fs.SkipDir for a folder skips its contents; any other error stops
+// the walk and is returned. Like walkDir, it doesn't walk into links, unless
+// links is set to follow them.
+func walkDirParallel(fsys fileSystem, root string, workers int, links *linkFollower, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = visitParallel(fsys, root, fs.FileInfoToDirEntry(info), workers, links, fn)
+	}
+
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+type parallelWalker struct {
+	fsys  fileSystem
+	links *linkFollower
+	fn    fs.WalkDirFunc
+	sem   chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+	err   error
+	stop  chan struct{}
+}
+
+func visitParallel(fsys fileSystem, root string, d fs.DirEntry, workers int, links *linkFollower, fn fs.WalkDirFunc) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	w := &parallelWalker{
+		fsys:  fsys,
+		links: links,
+		fn:    fn,
+		sem:   make(chan struct{}, workers-1),
+		stop:  make(chan struct{}),
+	}
+
+	if links != nil {
+		var ok bool
+		if d, ok = links.enter(fsys, root, d); !ok {
+			return nil
+		}
+	}
+	if err := fn(root, d, nil); err != nil || !d.IsDir() || isLink(d) {
+		return err
+	}
+
+	w.walk(root, d)
+	w.wg.Wait()
+	return w.err
+}
+
+func (w *parallelWalker) fail(err error) {
+	w.once.Do(func() {
+		w.err = err
+		close(w.stop)
+	})
+}
+
+func (w *parallelWalker) stopped() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// walk visits the contents of dir, which has already been passed to fn.
+// Sub folders are walked on a new goroutine if a worker is free, otherwise
+// on this one.
+func (w *parallelWalker) walk(dir string, d fs.DirEntry) {
+	entries, err := w.fsys.ReadDir(dir)
+	if err != nil {
+		if err = w.fn(dir, d, err); err != nil && !errors.Is(err, fs.SkipDir) {
+			w.fail(err)
+		}
+		return
+	}
+
+	for _, e := range entries {
+		if w.stopped() {
+			return
+		}
+
+		path := filepath.Join(dir, e.Name())
+		if w.links != nil {
+			var ok bool
+			if e, ok = w.links.enter(w.fsys, path, e); !ok {
+				continue
+			}
+		}
+		err := w.fn(path, e, nil)
+		if !e.IsDir() || isLink(e) {
+			if err != nil && err != fs.SkipDir {
+				w.fail(err)
+				return
+			}
+			continue
+		}
+
+		if err == fs.SkipDir {
+			continue
+		}
+		if err != nil {
+			w.fail(err)
+			return
+		}
+
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func(path string, e fs.DirEntry) {
+				defer w.wg.Done()
+				defer func() { <-w.sem }()
+				w.walk(path, e)
+			}(path, e)
+		default:
+			w.walk(path, e)
+		}
+	}
+}
+
+// linkFollower has a walk follow links to folders, keeping track of the
+// folders walked so a link back up the tree, or a second link to a folder
+// already walked, isn't walked again. One follower can be shared by several
+// walks so a folder reached from more than one start folder is only walked
+// once.
+type linkFollower struct {
+	mu  sync.Mutex
+	ids map[fileID]bool
+	// targets are the real paths of the folders links lead to, used where
+	// the platform doesn't report file IDs.
+	targets map[string]bool
+}
+
+func newLinkFollower() *linkFollower {
+	return &linkFollower{ids: make(map[fileID]bool), targets: make(map[string]bool)}
+}
+
+// enter returns the entry to walk for d at path: a folder for a link to one,
+// otherwise d. It reports false for a folder that has been walked already,
+// which is then skipped along with everything below it, and for a broken link.
+func (l *linkFollower) enter(fsys fileSystem, path string, d fs.DirEntry) (fs.DirEntry, bool) {
+	link := d.Type()&fs.ModeSymlink != 0
+	if !link && !d.IsDir() {
+		return d, true
+	}
+
+	var info fs.FileInfo
+	var err error
+	if link {
+		info, err = fsys.Stat(path)
+		if err != nil {
+			return d, false
+		}
+		if !info.IsDir() {
+			return d, true
+		}
+		d = fs.FileInfoToDirEntry(info)
+	} else if info, err = d.Info(); err != nil {
+		return d, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if id, _, ok := hardLinks(info); ok {
+		if l.ids[id] {
+			return d, false
+		}
+		l.ids[id] = true
+		return d, true
+	}
+
+	// Without file IDs only links are tracked, by where they lead, and a link
+	// to a folder above it is never followed.
+	if !link {
+		return d, true
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil || l.targets[target] {
+		return d, false
+	}
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil && !isBelowDir(target, parent) && target != parent {
+		l.targets[target] = true
+		return d, true
+	}
+	return d, false
+}
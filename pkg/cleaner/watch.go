@@ -0,0 +1,329 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// indexFile is versioned as entries in the original index.json have no
+	// reclaimable size.
+	indexFile     = "index-v2.json"
+	watchDebounce = 5 * time.Second
+)
+
+type indexEntry struct {
+	Path    string `json:"path"`
+	Project string `json:"project"`
+	usage
+	ModTime time.Time `json:"modTime"`
+}
+
+// Index is every target folder found under a start folder, kept up to date by
+// -watch so a later run with -use-index doesn't need to scan.
+type Index struct {
+	mu        sync.Mutex
+	FromDir   string                 `json:"fromDir"`
+	DiskUsage bool                   `json:"diskUsage,omitempty"`
+	Updated   time.Time              `json:"updated"`
+	Entries   map[string]*indexEntry `json:"entries"`
+}
+
+func indexPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "npm-cleaner", indexFile), nil
+}
+
+func loadIndex() (*Index, error) {
+	p, err := indexPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no index at %s, run with -watch first", p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return idx, nil
+}
+
+func (idx *Index) save() error {
+	p, err := indexPath()
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.Updated = time.Now()
+	data, err := json.Marshal(idx)
+	idx.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// indexCandidates returns the indexed target folders that pass the same
+// checks as a scan, with their sizes already known.
+func indexCandidates(o *Options) ([]*Folder, error) {
+	idx, err := loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Clean(idx.FromDir) != filepath.Clean(o.FromDir) {
+		return nil, fmt.Errorf("index is for %s, not %s", idx.FromDir, o.FromDir)
+	}
+	if idx.DiskUsage != o.DiskUsage {
+		return nil, fmt.Errorf("index was sized with -disk-usage=%t, run -watch again with -disk-usage=%t", idx.DiskUsage, o.DiskUsage)
+	}
+
+	paths := make([]string, 0, len(idx.Entries))
+	for p := range idx.Entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var candidates []*Folder
+	for _, p := range paths {
+		e := idx.Entries[p]
+		if _, err := o.files().Stat(e.Path); err != nil {
+			continue
+		}
+
+		f := &Folder{
+			Path:    e.Path,
+			Project: e.Project,
+			ModTime: e.ModTime,
+		}
+		f.setUsage(e.usage)
+
+		accepted, err := acceptCandidate(o, f)
+		if err != nil {
+			return nil, err
+		}
+		if accepted {
+			candidates = append(candidates, f)
+		}
+	}
+
+	return candidates, nil
+}
+
+// Watcher keeps an Index current from filesystem notifications. Every
+// scanned folder is watched, except the contents of target folders where
+// only the top level is watched to notice packages being added or removed.
+type Watcher struct {
+	o      *Options
+	idx    *Index
+	fsw    *fsnotify.Watcher
+	resize map[string]bool
+}
+
+// NewWatcher scans opts.FromDir to build the index and saves it, ready for
+// Run to keep it up to date.
+func NewWatcher(ctx context.Context, opts Options) (*Watcher, error) {
+	if !opts.real() {
+		return nil, errors.New("watching needs the real filesystem, not Options.FS")
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		o:      &opts,
+		idx:    &Index{FromDir: opts.FromDir, DiskUsage: opts.DiskUsage, Entries: make(map[string]*indexEntry)},
+		fsw:    fsw,
+		resize: make(map[string]bool),
+	}
+
+	if err := w.add(ctx, opts.FromDir); err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+	if err := w.idx.save(); err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Len returns the number of target folders in the index.
+func (w *Watcher) Len() int {
+	w.idx.mu.Lock()
+	defer w.idx.mu.Unlock()
+	return len(w.idx.Entries)
+}
+
+// Run keeps the index up to date, saving it shortly after each change, until
+// ctx is cancelled. The index is saved before returning.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer w.fsw.Close()
+
+	flush := time.NewTimer(watchDebounce)
+	flush.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.flush(context.Background())
+			return w.idx.save()
+		case err := <-w.fsw.Errors:
+			w.o.warn(fmt.Errorf("watch error: %w", err))
+		case ev := <-w.fsw.Events:
+			if w.handle(ctx, ev) {
+				flush.Reset(watchDebounce)
+			}
+		case <-flush.C:
+			w.flush(ctx)
+			if err := w.idx.save(); err != nil {
+				w.o.warn(fmt.Errorf("error saving index: %w", err))
+			}
+		}
+	}
+}
+
+// add walks root, indexing target folders and watching every other folder.
+func (w *Watcher) add(ctx context.Context, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil || path == root {
+				return err
+			}
+			return fs.SkipDir
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if skipFolder(w.o, w.o.FromDir, path, d) {
+			return fs.SkipDir
+		}
+
+		if err := w.fsw.Add(path); err != nil {
+			w.o.warn(fmt.Errorf("not watching %s: %w", path, err))
+		}
+
+		project, ok := matchTarget(osFS{}, w.o.Targets, path)
+		if !ok {
+			return nil
+		}
+
+		w.index(ctx, path, project)
+		return fs.SkipDir
+	})
+}
+
+func (w *Watcher) index(ctx context.Context, path, project string) {
+	u, err := folderSize(ctx, osFS{}, path, w.o.DiskUsage)
+	if err != nil {
+		return
+	}
+	modTime, err := projectModTime(w.o, project, path)
+	if err != nil {
+		return
+	}
+
+	w.idx.mu.Lock()
+	w.idx.Entries[path] = &indexEntry{
+		Path:    path,
+		Project: project,
+		usage:   u,
+		ModTime: modTime,
+	}
+	w.idx.mu.Unlock()
+}
+
+// handle updates the index for a single event, reporting whether anything
+// changed.
+func (w *Watcher) handle(ctx context.Context, ev fsnotify.Event) bool {
+	dir := filepath.Dir(ev.Name)
+
+	w.idx.mu.Lock()
+	_, isTarget := w.idx.Entries[ev.Name]
+	_, inTarget := w.idx.Entries[dir]
+	w.idx.mu.Unlock()
+
+	switch {
+	case isTarget && ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		w.idx.mu.Lock()
+		delete(w.idx.Entries, ev.Name)
+		w.idx.mu.Unlock()
+	case inTarget:
+		// Packages added to or removed from a target folder.
+		w.resize[dir] = true
+	case ev.Op&fsnotify.Create != 0:
+		if info, err := os.Lstat(ev.Name); err == nil && info.IsDir() {
+			_ = w.add(ctx, ev.Name)
+		}
+		w.touchProject(ev.Name)
+	case ev.Op&(fsnotify.Write|fsnotify.Remove|fsnotify.Rename) != 0:
+		w.touchProject(ev.Name)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// touchProject marks the project containing path as modified now.
+func (w *Watcher) touchProject(path string) {
+	w.idx.mu.Lock()
+	defer w.idx.mu.Unlock()
+	for _, e := range w.idx.Entries {
+		if strings.HasPrefix(path, e.Project+string(filepath.Separator)) {
+			e.ModTime = time.Now()
+		}
+	}
+}
+
+// flush re-sizes target folders that have changed since the last flush.
+func (w *Watcher) flush(ctx context.Context) {
+	for path := range w.resize {
+		w.idx.mu.Lock()
+		e, ok := w.idx.Entries[path]
+		w.idx.mu.Unlock()
+		if ok {
+			if u, err := folderSize(ctx, osFS{}, path, w.o.DiskUsage); err == nil {
+				w.idx.mu.Lock()
+				e.usage = u
+				w.idx.mu.Unlock()
+			}
+		}
+		delete(w.resize, path)
+	}
+}
@@ -0,0 +1,167 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// workspaces finds the npm, yarn and pnpm workspace roots that projects
+// belong to, remembering what it has read.
+type workspaces struct {
+	fsys     fileSystem
+	patterns map[string][]string
+}
+
+func newWorkspaces(fsys fileSystem) *workspaces {
+	return &workspaces{fsys: fsys, patterns: make(map[string][]string)}
+}
+
+// packagePatterns returns the globs for the workspace packages of the
+// project at dir, from the workspaces field of its package.json or from
+// pnpm-workspace.yaml, or nil if it isn't a workspace root.
+func (w *workspaces) packagePatterns(dir string) []string {
+	if patterns, ok := w.patterns[dir]; ok {
+		return patterns
+	}
+
+	var patterns []string
+	if data, err := w.fsys.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Workspaces json.RawMessage `json:"workspaces"`
+		}
+		if json.Unmarshal(data, &pkg) == nil && len(pkg.Workspaces) > 0 {
+			// Either a list of globs or, for yarn, {"packages": [...]}.
+			var nested struct {
+				Packages []string `json:"packages"`
+			}
+			if json.Unmarshal(pkg.Workspaces, &patterns) != nil && json.Unmarshal(pkg.Workspaces, &nested) == nil {
+				patterns = nested.Packages
+			}
+		}
+	}
+	if data, err := w.fsys.ReadFile(filepath.Join(dir, "pnpm-workspace.yaml")); err == nil {
+		patterns = append(patterns, pnpmWorkspacePackages(string(data))...)
+	}
+
+	w.patterns[dir] = patterns
+	return patterns
+}
+
+// pnpmWorkspacePackages reads the packages list from pnpm-workspace.yaml,
+// which is all that is needed from it, without a YAML parser.
+func pnpmWorkspacePackages(yaml string) []string {
+	var patterns []string
+	inPackages := false
+	for _, line := range strings.Split(yaml, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "-") {
+			inPackages = strings.HasPrefix(trimmed, "packages:")
+			continue
+		}
+		if inPackages && strings.HasPrefix(trimmed, "- ") {
+			patterns = append(patterns, strings.Trim(strings.TrimSpace(trimmed[2:]), `"'`))
+		}
+	}
+	return patterns
+}
+
+// root returns the workspace root that the project at dir is a package of,
+// or "" if it isn't in one.
+func (w *workspaces) root(dir string) string {
+	for child, parent := dir, filepath.Dir(dir); parent != child; child, parent = parent, filepath.Dir(parent) {
+		patterns := w.packagePatterns(parent)
+		if len(patterns) == 0 {
+			continue
+		}
+		rel, err := filepath.Rel(parent, dir)
+		if err == nil && matchWorkspace(patterns, filepath.ToSlash(rel)) {
+			return parent
+		}
+	}
+	return ""
+}
+
+// matchWorkspace reports whether rel, a slash separated path from the
+// workspace root, matches one of its package globs, allowing for ** and
+// patterns excluded with !.
+func matchWorkspace(patterns []string, rel string) bool {
+	matched := false
+	for _, p := range patterns {
+		exclude := strings.HasPrefix(p, "!")
+		p = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(p, "!"), "./"), "/")
+		ok := false
+		if i := strings.Index(p, "**"); i >= 0 {
+			ok = strings.HasPrefix(rel, p[:i])
+		} else {
+			ok, _ = path.Match(p, rel)
+		}
+		if ok {
+			matched = !exclude
+		}
+	}
+	return matched
+}
+
+// groupWorkspaces makes the target folders of each workspace's packages
+// members of the folder in the workspace root, so a monorepo is listed and
+// deleted as a whole with the combined size and the newest age. Packages
+// whose workspace root has a folder that wasn't found, such as one modified
+// too recently or kept, are dropped, as deleting only some of a workspace's
+// folders leaves it broken anyway. Other folders are returned as they are.
+func groupWorkspaces(o *Options, folders []*Folder) []*Folder {
+	w := newWorkspaces(o.files())
+	byPath := make(map[string]*Folder, len(folders))
+	for _, f := range folders {
+		byPath[f.Path] = f
+	}
+
+	grouped := folders[:0:0]
+	for _, f := range folders {
+		// Each package's build output stands on its own.
+		root := ""
+		if f.Project != "" && !f.Artifact {
+			root = w.root(f.Project)
+		}
+		if root == "" {
+			grouped = append(grouped, f)
+			continue
+		}
+
+		// The same target in the root, such as .yarn/cache, which may be
+		// more than one folder deep.
+		rel, err := filepath.Rel(f.Project, f.Path)
+		if err != nil {
+			grouped = append(grouped, f)
+			continue
+		}
+		rootPath := filepath.Join(root, rel)
+		rootFolder := byPath[rootPath]
+		if rootFolder == nil {
+			if _, err := o.files().Lstat(rootPath); err == nil {
+				o.skipped(f, SkipWorkspace)
+				continue
+			}
+			grouped = append(grouped, f)
+			continue
+		}
+		rootFolder.addMember(f)
+	}
+	return grouped
+}
+
+// addMember adds m, the folder of a workspace package, to f, the folder in
+// the workspace root.
+func (f *Folder) addMember(m *Folder) {
+	f.Members = append(f.Members, m.Path)
+	f.SizeBytes += m.SizeBytes
+	f.ReclaimableBytes += m.ReclaimableBytes
+	f.PackageCount += m.PackageCount
+	if m.ModTime.After(f.ModTime) {
+		f.ModTime, f.ModDaysAgo = m.ModTime, m.ModDaysAgo
+	}
+}
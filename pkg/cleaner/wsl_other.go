@@ -0,0 +1,9 @@
+//go:build !windows
+
+package cleaner
+
+// wslDirs returns no folders, as WSL distributions are only reached from
+// Windows.
+func wslDirs() []string {
+	return nil
+}
@@ -0,0 +1,24 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// wslRoot is where Windows shows the filesystems of WSL distributions.
+const wslRoot = `\\wsl$`
+
+// wslDirs returns the home folders of every running WSL distribution, where
+// projects built inside WSL live.
+func wslDirs() []string {
+	distros, err := os.ReadDir(wslRoot)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, d := range distros {
+		dirs = append(dirs, filepath.Join(wslRoot, d.Name(), "home"), filepath.Join(wslRoot, d.Name(), "root"))
+	}
+	return dirs
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// planVersion is bumped whenever the plan format changes in a way older
+// versions can't read.
+const planVersion = 1
+
+// plan is the list of folders saved by scan -o, to be reviewed, edited and
+// deleted later with clean -from-plan without scanning again.
+type plan struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Folders   []planFolder `json:"folders"`
+}
+
+type planFolder struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"sizeBytes"`
+	// ModTime is when the project was last modified at the time of the scan.
+	// A project modified since is left alone.
+	ModTime time.Time `json:"modTime"`
+}
+
+// writePlan saves the folders in results that would be deleted to p.
+func writePlan(p string, results *cleaner.Result) error {
+	pl := plan{
+		Version:   planVersion,
+		CreatedAt: time.Now(),
+		Folders:   make([]planFolder, 0, len(results.Folders)),
+	}
+	for _, f := range results.Folders {
+		pl.Folders = append(pl.Folders, planFolder{Path: f.Path, SizeBytes: f.SizeBytes, ModTime: f.ModTime})
+	}
+
+	data, err := json.MarshalIndent(pl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(data, '\n'), 0o644)
+}
+
+// readPlan loads a plan saved by writePlan.
+func readPlan(p string) (*plan, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var pl plan
+	if err := json.Unmarshal(data, &pl); err != nil {
+		return nil, fmt.Errorf("reading plan %s: %w", p, err)
+	}
+	if pl.Version != planVersion {
+		return nil, fmt.Errorf("plan %s is version %d, this version of npm-cleaner reads version %d", p, pl.Version, planVersion)
+	}
+	return &pl, nil
+}
+
+// apply sets c to look at only the folders in the plan, whatever their age or
+// size, skipping any whose project has been modified since the plan was made.
+func (pl *plan) apply(c *Config) {
+	c.Paths = make([]string, 0, len(pl.Folders))
+	c.PlannedModTimes = make(map[string]time.Time, len(pl.Folders))
+	for _, f := range pl.Folders {
+		c.Paths = append(c.Paths, f.Path)
+		c.PlannedModTimes[f.Path] = f.ModTime
+	}
+	c.Exact = true
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// PorcelainVersion starts every -porcelain line. It only changes if the
+// format does, so scripts can check it. Version 1 printed paths unquoted.
+const PorcelainVersion = "2"
+
+// Statuses in -porcelain output.
+const (
+	PorcelainCandidate = "candidate"
+	PorcelainReview    = "review"
+	PorcelainDirty     = "dirty"
+	PorcelainDeleted   = "deleted"
+	PorcelainTrashed   = "trashed"
+	PorcelainFailed    = "failed"
+)
+
+// runPorcelain writes one line per folder, and one more per folder deleted
+// or failed to delete, in the -porcelain format and returns the exit code.
+// Before deleting, it asks on stderr as the table output does, reading the
+// answers from stdin.
+func runPorcelain(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, c *Config, results *cleaner.Result, scanErr error) int {
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+
+	now := time.Now()
+	if results != nil {
+		writePorcelain(w, results.Folders, PorcelainCandidate, now)
+		writePorcelain(w, results.Review, PorcelainReview, now)
+		writePorcelain(w, results.Dirty, PorcelainDirty, now)
+	}
+	if scanErr != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %s\n", scanErr)
+		return ExitError
+	}
+	for _, err := range results.Errors {
+		_, _ = fmt.Fprintf(stderr, "error: %s\n", err)
+	}
+	if !c.delete || len(results.Folders) == 0 {
+		return c.scanExitCode(results)
+	}
+
+	ok, err := chooseToDelete(stdin, stderr, results, c)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %s\n", err)
+		return ExitError
+	}
+	if !ok {
+		return c.scanExitCode(results)
+	}
+
+	sizes := make(map[string]*cleaner.Folder, len(results.Folders))
+	for _, f := range results.Folders {
+		sizes[f.Path] = f
+	}
+
+	deleteCtx, stopDelete := signal.NotifyContext(ctx, os.Interrupt)
+	defer stopDelete()
+
+	code := c.scanExitCode(results)
+	c.recordRestore(results)
+	deleted := cleaner.Delete(deleteCtx, results, c.Options, func(r cleaner.DeleteResult) bool {
+		status := PorcelainDeleted
+		switch {
+		case r.Err != nil:
+			status = PorcelainFailed
+			code = c.exitCode(FailOnDeleteError)
+			_, _ = fmt.Fprintf(stderr, "error deleting %s: %s\n", r.Path, r.Err)
+		case r.Trashed:
+			status = PorcelainTrashed
+		}
+		writePorcelain(w, []*cleaner.Folder{sizes[r.Path]}, status, now)
+		_ = w.Flush()
+		return true
+	})
+	c.recordHistory(results, deleted)
+	c.writeMetrics(results, deleted)
+	c.notifyDone(results, deleted)
+	return code
+}
+
+// writePorcelain writes a line for each folder:
+//
+//	2 <status> <size bytes> <age seconds> <path>
+//
+// separated by single spaces, with the age as of now. The path is quoted as
+// a Go string, so one with spaces, quotes or newlines still fits on its line
+// and strconv.Unquote gives it back exactly.
+func writePorcelain(w io.Writer, folders []*cleaner.Folder, status string, now time.Time) {
+	for _, f := range folders {
+		age := int64(now.Sub(f.ModTime) / time.Second)
+		_, _ = fmt.Fprintf(w, "%s %s %d %d %s\n", PorcelainVersion, status, f.SizeBytes, age, strconv.Quote(f.Path))
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+func TestWritePorcelain(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/home/me/app/node_modules", want: `2 candidate 1024 90 "/home/me/app/node_modules"` + "\n"},
+		{path: "/home/me/old app/node_modules", want: `2 candidate 1024 90 "/home/me/old app/node_modules"` + "\n"},
+		{path: "/home/me/ app /node_modules", want: `2 candidate 1024 90 "/home/me/ app /node_modules"` + "\n"},
+		{path: "/home/me/new\nline/node_modules", want: `2 candidate 1024 90 "/home/me/new\nline/node_modules"` + "\n"},
+		{path: `/home/me/"quoted"\app/node_modules`, want: `2 candidate 1024 90 "/home/me/\"quoted\"\\app/node_modules"` + "\n"},
+		{path: "/home/me/café/node_modules", want: `2 candidate 1024 90 "/home/me/café/node_modules"` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			f := &cleaner.Folder{Path: tt.path, SizeBytes: 1024, ModTime: now.Add(-90 * time.Second)}
+			var out bytes.Buffer
+			writePorcelain(&out, []*cleaner.Folder{f}, PorcelainCandidate, now)
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const progressMaxWidth = 79
+
+var spinner = []string{"|", "/", "-", "\\"}
+
+// progress writes a single status line, prefixed with a spinner, that is
+// overwritten on each update. It does nothing unless out is a terminal,
+// redraws at most every 100ms, and is safe to update from many goroutines.
+type progress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	enabled bool
+	last    time.Time
+	width   int
+	frame   int
+}
+
+func newProgress(out io.Writer) *progress {
+	f, ok := out.(*os.File)
+	return &progress{
+		out:     out,
+		enabled: ok && isInteractive(f),
+	}
+}
+
+func (p *progress) update(format string, args ...interface{}) {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.last) < 100*time.Millisecond {
+		return
+	}
+	p.last = time.Now()
+	p.frame = (p.frame + 1) % len(spinner)
+
+	line := spinner[p.frame] + " " + fmt.Sprintf(format, args...)
+	if len(line) > progressMaxWidth {
+		line = line[:progressMaxWidth-3] + "..."
+	}
+
+	pad := ""
+	if len(line) < p.width {
+		pad = strings.Repeat(" ", p.width-len(line))
+	}
+	p.width = len(line)
+	_, _ = fmt.Fprintf(p.out, "\r%s%s", line, pad)
+}
+
+// printf writes a line of its own, clearing the status line first so it
+// isn't left mixed into it. The status line is redrawn on the next update.
+func (p *progress) printf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.enabled && p.width > 0 {
+		_, _ = fmt.Fprintf(p.out, "\r%s\r", strings.Repeat(" ", p.width))
+		p.width = 0
+		p.last = time.Time{}
+	}
+	_, _ = fmt.Fprintf(p.out, format, args...)
+}
+
+func (p *progress) clear() {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.width == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(p.out, "\r%s\r", strings.Repeat(" ", p.width))
+	p.width = 0
+	p.last = time.Time{}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+var errProjectsPaths = errors.New("projects scans from -from and the folders listed after the flags, -exact and -stdin can't be used with it")
+
+type jsonProject struct {
+	Path             string    `json:"path"`
+	NodeModules      bool      `json:"nodeModules"`
+	NodeModulesBytes int64     `json:"nodeModulesBytes"`
+	PackageManager   string    `json:"packageManager,omitempty"`
+	LastActivity     time.Time `json:"lastActivity"`
+	ModDaysAgo       int       `json:"modDaysAgo"`
+}
+
+// runProjects lists every project found, whether or not it has anything to
+// delete, and returns the exit code.
+func runProjects(ctx context.Context, out io.Writer, c *Config) int {
+	if c.Paths != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", errProjectsPaths)
+		return ExitError
+	}
+
+	projects, err := cleaner.FindProjects(ctx, c.Options)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+		return ExitError
+	}
+
+	if c.json {
+		list := make([]jsonProject, 0, len(projects))
+		for _, p := range projects {
+			list = append(list, jsonProject{p.Path, p.NodeModules, p.NodeModulesBytes, p.PackageManager, p.LastActivity, p.ModDaysAgo})
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(list); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s", err)
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	if len(projects) == 0 {
+		_, _ = fmt.Fprintf(out, "No projects found\n")
+		return ExitOK
+	}
+	printProjects(out, projects)
+	return ExitOK
+}
+
+// printProjects prints projects as a table, then how many have node_modules
+// and their total size.
+func printProjects(out io.Writer, projects []*cleaner.Project) {
+	longestPath := len("Path")
+	for _, p := range projects {
+		if len(p.Path) > longestPath {
+			longestPath = len(p.Path)
+		}
+	}
+	longestPath++
+
+	row := func(path, nodeModules, size, manager, days string) {
+		_, _ = fmt.Fprintf(out, "%-"+strconv.Itoa(longestPath)+"s|%13s|%12s|%16s|%20s\n", path, nodeModules, size, manager, days)
+	}
+
+	installed := 0
+	var total int64
+	row("Path", "node_modules", "Size", "Package Manager", "Modified Days Ago")
+	for _, p := range projects {
+		nodeModules, size := "no", ""
+		if p.NodeModules {
+			nodeModules, size = "yes", cleaner.FormatSize(p.NodeModulesBytes)
+			installed++
+			total += p.NodeModulesBytes
+		}
+		row(p.Path, nodeModules, size, p.PackageManager, groupThousands(p.ModDaysAgo))
+	}
+	_, _ = fmt.Fprintf(out, "\n%d projects, %d with node_modules taking %s\n", len(projects), installed, cleaner.FormatSize(total))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+const restoreFile = "restore.json"
+
+// restoreEntry is a project whose node_modules was deleted, and the command
+// that reinstalls it.
+type restoreEntry struct {
+	Project        string    `json:"project"`
+	PackageManager string    `json:"packageManager,omitempty"`
+	Command        []string  `json:"command"`
+	DeletedAt      time.Time `json:"deletedAt"`
+}
+
+func restoreFilePath() (string, error) {
+	p, err := historyFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), restoreFile), nil
+}
+
+// readRestore returns the projects in the restore file at p that are still
+// to be restored, sorted by path. Those that have a node_modules again, or
+// are gone, are left out.
+func readRestore(p string) ([]restoreEntry, error) {
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []restoreEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", p, err)
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(e.Project, "package.json")); err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(e.Project, "node_modules")); err == nil {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Project < kept[j].Project })
+	return kept, nil
+}
+
+func writeRestore(p string, entries []restoreEntry) error {
+	if len(entries) == 0 {
+		err := os.Remove(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(data, '\n'), 0o644)
+}
+
+// recordRestore adds the projects of the node_modules folders about to be
+// deleted to the restore file, with the command that reinstalls each from its
+// lockfile, for the restore command. It is written before deleting so a run
+// that is killed part way is covered too; projects whose folder ends up not
+// being deleted are dropped the next time it is read. Failing to write it is
+// only a warning.
+func (c *Config) recordRestore(results *cleaner.Result) {
+	if !c.history {
+		return
+	}
+
+	p, err := restoreFilePath()
+	if err == nil {
+		err = addRestore(p, results.Folders)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: recording projects to restore: %s\n", err)
+	}
+}
+
+func addRestore(p string, folders []*cleaner.Folder) error {
+	entries, err := readRestore(p)
+	if err != nil {
+		return err
+	}
+
+	byProject := make(map[string]int, len(entries))
+	for i, e := range entries {
+		byProject[e.Project] = i
+	}
+	for _, f := range folders {
+		if f.Project == "" || filepath.Base(f.Path) != "node_modules" {
+			continue
+		}
+		project, err := filepath.Abs(f.Project)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(project, "package.json")); err != nil {
+			continue
+		}
+
+		pm := cleaner.DetectPackageManager(project)
+		e := restoreEntry{Project: project, PackageManager: pm, Command: cleaner.InstallCommand(project, pm), DeletedAt: time.Now()}
+		if i, ok := byProject[project]; ok {
+			entries[i] = e
+		} else {
+			byProject[project] = len(entries)
+			entries = append(entries, e)
+		}
+	}
+	return writeRestore(p, entries)
+}
+
+// restoreTarget is a folder to bring back, from its archive if it has one
+// and otherwise by reinstalling its project.
+type restoreTarget struct {
+	folder  string
+	archive string
+	entry   restoreEntry
+}
+
+// runRestore brings back the folders listed after the flags, each a deleted
+// folder or its project, or every project in the restore file, one at a
+// time. A folder archived to -archive is extracted from it, and otherwise its
+// project's dependencies are reinstalled. With -print it prints the commands
+// as a script instead.
+func runRestore(out io.Writer, c *Config) error {
+	p, err := restoreFilePath()
+	if err != nil {
+		return err
+	}
+	entries, err := readRestore(p)
+	if err != nil {
+		return err
+	}
+
+	var chosen []restoreTarget
+	if len(c.args) == 0 {
+		for _, e := range entries {
+			folder := filepath.Join(e.Project, "node_modules")
+			chosen = append(chosen, restoreTarget{folder: folder, archive: c.findArchive(folder), entry: e})
+		}
+	}
+	for _, arg := range c.args {
+		t, ok := c.findRestoreTarget(entries, arg)
+		if !ok {
+			return fmt.Errorf("nothing to restore for %s, it has no archive in -archive and no deleted node_modules recorded", arg)
+		}
+		chosen = append(chosen, t)
+	}
+	if len(chosen) == 0 {
+		_, _ = fmt.Fprintf(out, "No projects to restore\n")
+		return nil
+	}
+
+	if c.restorePrint {
+		for _, t := range chosen {
+			if t.archive != "" {
+				_, _ = fmt.Fprintf(out, "%s\n", restoreScriptLine(filepath.Dir(t.folder), []string{"tar", "-xf", t.archive}))
+			} else {
+				_, _ = fmt.Fprintf(out, "%s\n", restoreScriptLine(t.entry.Project, t.entry.Command))
+			}
+		}
+		return nil
+	}
+
+	failed := 0
+	for _, t := range chosen {
+		if err := restoreFolder(out, t); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "error restoring %s: %s\n", t.folder, err)
+			failed++
+		}
+	}
+
+	// Restored projects have a node_modules again, so reading the file drops
+	// them.
+	if entries, err := readRestore(p); err == nil {
+		_ = writeRestore(p, entries)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d folders couldn't be restored", failed, len(chosen))
+	}
+	return nil
+}
+
+func restoreFolder(out io.Writer, t restoreTarget) error {
+	if t.archive != "" {
+		_, _ = fmt.Fprintf(out, "Extracting %s from %s\n", t.folder, t.archive)
+		return cleaner.ExtractArchive(t.archive, t.folder)
+	}
+
+	_, _ = fmt.Fprintf(out, "Restoring %s with %s\n", t.entry.Project, strings.Join(t.entry.Command, " "))
+	cmd := exec.Command(t.entry.Command[0], t.entry.Command[1:]...)
+	cmd.Dir = t.entry.Project
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// findRestoreTarget finds how to restore the folder at path, or the
+// node_modules of the project at path.
+func (c *Config) findRestoreTarget(entries []restoreEntry, path string) (restoreTarget, bool) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	folders := []string{path}
+	if filepath.Base(path) != "node_modules" {
+		folders = append(folders, filepath.Join(path, "node_modules"))
+	}
+	for _, folder := range folders {
+		if archive := c.findArchive(folder); archive != "" {
+			return restoreTarget{folder: folder, archive: archive}, true
+		}
+	}
+
+	project := path
+	if filepath.Base(path) == "node_modules" {
+		project = filepath.Dir(path)
+	}
+	for _, e := range entries {
+		if e.Project == project {
+			return restoreTarget{folder: filepath.Join(project, "node_modules"), entry: e}, true
+		}
+	}
+	return restoreTarget{}, false
+}
+
+// findArchive returns the archive of the folder in -archive, if there is one
+// and the folder isn't there already.
+func (c *Config) findArchive(folder string) string {
+	if c.ArchiveDir == "" {
+		return ""
+	}
+	if _, err := os.Lstat(folder); err == nil {
+		return ""
+	}
+	return cleaner.FindArchive(c.ArchiveDir, folder)
+}
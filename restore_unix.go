@@ -0,0 +1,21 @@
+//go:build !windows
+
+package main
+
+import "strings"
+
+// restoreScriptLine is the shell command that runs command in project.
+func restoreScriptLine(project string, command []string) string {
+	quoted := make([]string, len(command))
+	for i, a := range command {
+		quoted[i] = shellQuote(a)
+	}
+	return "cd " + shellQuote(project) + " && " + strings.Join(quoted, " ")
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./:,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,12 @@
+package main
+
+import "strings"
+
+// restoreScriptLine is the cmd.exe command that runs command in project.
+func restoreScriptLine(project string, command []string) string {
+	quoted := make([]string, len(command))
+	for i, a := range command {
+		quoted[i] = windowsQuote(a)
+	}
+	return "cd /d " + windowsQuote(project) + " && " + strings.Join(quoted, " ")
+}
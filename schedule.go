@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	ScheduleHourly = "hourly"
+	ScheduleDaily  = "daily"
+	ScheduleWeekly = "weekly"
+
+	scheduleName = "npm-cleaner"
+)
+
+const scheduleUsage = `usage:
+  npm-cleaner schedule [-every hourly|daily|weekly] [flags]  install a recurring run with the given flags
+  npm-cleaner schedule status                                show the installed schedule
+  npm-cleaner schedule remove                                remove the installed schedule
+`
+
+// runSchedule handles the schedule subcommand, installing, removing or
+// showing a recurring run using cron, launchd or Task Scheduler.
+func runSchedule(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "status":
+			status, err := scheduleStatus()
+			if err != nil {
+				return err
+			}
+			fmt.Println(status)
+			return nil
+		case "remove":
+			if err := removeSchedule(); err != nil {
+				return err
+			}
+			fmt.Println("Schedule removed")
+			return nil
+		case "-h", "-help", "--help", "help":
+			fmt.Print(scheduleUsage)
+			return nil
+		}
+	}
+
+	every, flags, err := splitScheduleArgs(args)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	if err := installSchedule(every, append([]string{exe}, flags...)); err != nil {
+		return err
+	}
+	fmt.Printf("Scheduled to run %s: %s\n", every, strings.Join(append([]string{exe}, flags...), " "))
+	return nil
+}
+
+// splitScheduleArgs pulls the -every option out of args, leaving the flags
+// to run the cleaner with.
+func splitScheduleArgs(args []string) (string, []string, error) {
+	every := ScheduleWeekly
+	var flags []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := strings.TrimLeft(arg, "-")
+		switch {
+		case arg != name && name == "every":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("-every needs a value\n%s", scheduleUsage)
+			}
+			every = args[i+1]
+			i++
+		case arg != name && strings.HasPrefix(name, "every="):
+			every = strings.TrimPrefix(name, "every=")
+		default:
+			flags = append(flags, arg)
+		}
+	}
+
+	switch every {
+	case ScheduleHourly, ScheduleDaily, ScheduleWeekly:
+		return every, flags, nil
+	}
+	return "", nil, fmt.Errorf("unknown -every %q\n%s", every, scheduleUsage)
+}
@@ -0,0 +1,93 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const cronTag = "# " + scheduleName
+
+var cronTimes = map[string]string{
+	ScheduleHourly: "0 * * * *",
+	ScheduleDaily:  "0 3 * * *",
+	ScheduleWeekly: "0 3 * * 0",
+}
+
+func installSchedule(every string, argv []string) error {
+	lines, err := crontabLines()
+	if err != nil {
+		return err
+	}
+
+	// cron turns an unescaped % into a newline, even inside quotes.
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		quoted[i] = strings.ReplaceAll(shellQuote(a), "%", `\%`)
+	}
+	lines = append(withoutCronTag(lines), fmt.Sprintf("%s %s %s", cronTimes[every], strings.Join(quoted, " "), cronTag))
+	return writeCrontab(lines)
+}
+
+func removeSchedule() error {
+	lines, err := crontabLines()
+	if err != nil {
+		return err
+	}
+	return writeCrontab(withoutCronTag(lines))
+}
+
+func scheduleStatus() (string, error) {
+	lines, err := crontabLines()
+	if err != nil {
+		return "", err
+	}
+	for _, l := range lines {
+		if strings.HasSuffix(l, cronTag) {
+			return "Installed in crontab: " + strings.TrimSpace(strings.TrimSuffix(l, cronTag)), nil
+		}
+	}
+	return "Not scheduled", nil
+}
+
+// crontabLines returns the current user's crontab. Having no crontab is not
+// an error.
+func crontabLines() ([]string, error) {
+	out, err := exec.Command("crontab", "-l").Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	text := strings.TrimRight(string(out), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+func writeCrontab(lines []string) error {
+	cmd := exec.Command("crontab", "-")
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("crontab: %s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+func withoutCronTag(lines []string) []string {
+	kept := lines[:0:0]
+	for _, l := range lines {
+		if !strings.HasSuffix(l, cronTag) {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const launchdLabel = "com.github.mikemherron.npm-cleaner"
+
+func launchdPlistPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist"), nil
+}
+
+func installSchedule(every string, argv []string) error {
+	p, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+
+	var args strings.Builder
+	for _, a := range argv {
+		args.WriteString("\t\t<string>")
+		if err := xml.EscapeText(&args, []byte(a)); err != nil {
+			return err
+		}
+		args.WriteString("</string>\n")
+	}
+
+	var interval string
+	switch every {
+	case ScheduleHourly:
+		interval = "<key>StartInterval</key>\n\t<integer>3600</integer>"
+	case ScheduleDaily:
+		interval = "<key>StartCalendarInterval</key>\n\t<dict>\n\t\t<key>Hour</key>\n\t\t<integer>3</integer>\n\t</dict>"
+	default:
+		interval = "<key>StartCalendarInterval</key>\n\t<dict>\n\t\t<key>Weekday</key>\n\t\t<integer>0</integer>\n\t\t<key>Hour</key>\n\t\t<integer>3</integer>\n\t</dict>"
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	%s
+</dict>
+</plist>
+`, launchdLabel, args.String(), interval)
+
+	_ = exec.Command("launchctl", "unload", p).Run()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(p, []byte(plist), 0644); err != nil {
+		return err
+	}
+	return launchctl("load", "-w", p)
+}
+
+func removeSchedule() error {
+	p, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return nil
+	}
+
+	_ = launchctl("unload", "-w", p)
+	return os.Remove(p)
+}
+
+func scheduleStatus() (string, error) {
+	p, err := launchdPlistPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return "Not scheduled", nil
+	}
+
+	if exec.Command("launchctl", "list", launchdLabel).Run() != nil {
+		return "Installed at " + p + " but not loaded", nil
+	}
+	return "Installed and loaded from " + p, nil
+}
+
+func launchctl(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("launchctl", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("launchctl: %s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var schtasksSchedules = map[string][]string{
+	ScheduleHourly: {"/SC", "HOURLY"},
+	ScheduleDaily:  {"/SC", "DAILY", "/ST", "03:00"},
+	ScheduleWeekly: {"/SC", "WEEKLY", "/D", "SUN", "/ST", "03:00"},
+}
+
+func installSchedule(every string, argv []string) error {
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		quoted[i] = windowsQuote(a)
+	}
+
+	args := append([]string{"/Create", "/F", "/TN", scheduleName, "/TR", strings.Join(quoted, " ")}, schtasksSchedules[every]...)
+	_, err := schtasks(args...)
+	return err
+}
+
+func removeSchedule() error {
+	if _, err := schtasks("/Query", "/TN", scheduleName); err != nil {
+		return nil
+	}
+	_, err := schtasks("/Delete", "/F", "/TN", scheduleName)
+	return err
+}
+
+func scheduleStatus() (string, error) {
+	out, err := schtasks("/Query", "/TN", scheduleName, "/FO", "LIST")
+	if err != nil {
+		return "Not scheduled", nil
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func schtasks(args ...string) (string, error) {
+	out, err := exec.Command("schtasks", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("schtasks: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return string(out), nil
+}
+
+func windowsQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
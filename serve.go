@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+var errNoToken = errors.New("serve needs a -token, or NPMCLEANER_TOKEN, for clients to send as a bearer token")
+
+// server runs scans and deletions for remote clients over HTTP. Only one scan
+// or deletion runs at a time, and only folders found by the last scan can be
+// deleted.
+type server struct {
+	c *Config
+	// ctx lasts as long as the server, so a client disconnecting doesn't
+	// stop a deletion part way through.
+	ctx context.Context
+
+	mu        sync.Mutex
+	busy      string
+	results   *cleaner.Result
+	scannedAt time.Time
+	scanErr   error
+	deleted   []cleaner.DeleteResult
+}
+
+type serveStatus struct {
+	Status    string      `json:"status"`
+	ScannedAt *time.Time  `json:"scannedAt,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Results   *jsonReport `json:"results,omitempty"`
+}
+
+type deleteRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// runServe serves the JSON API on -addr until interrupted:
+//
+//	POST /scan     start a scan in the background
+//	GET  /results  the status and the results of the last scan
+//	POST /delete   delete {"paths": [...]}, each found by the last scan
+//	GET  /metrics  the -metrics-file gauges for the last scan and deletion
+func runServe(c *Config) error {
+	if c.token == "" {
+		return errNoToken
+	}
+	c.Progress = nil
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s := &server{c: c, ctx: ctx}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scan", s.handle(http.MethodPost, s.scan))
+	mux.HandleFunc("/results", s.handle(http.MethodGet, s.status))
+	mux.HandleFunc("/delete", s.handle(http.MethodPost, s.delete))
+	mux.HandleFunc("/metrics", s.handle(http.MethodGet, s.metrics))
+
+	srv := &http.Server{Addr: c.addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	// Shutdown waits for deletions in progress to finish their folders.
+	shutdown := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+		close(shutdown)
+	}()
+
+	_, _ = fmt.Fprintf(os.Stderr, "Serving on %s\n", c.addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-shutdown
+	return nil
+}
+
+// handle wraps h to check the method and the bearer token, sent as
+// "Authorization: Bearer <token>".
+func (s *server) handle(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if token == auth || subtle.ConstantTimeCompare([]byte(token), []byte(s.c.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *server) scan(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy != "" {
+		http.Error(w, "already "+s.busy, http.StatusConflict)
+		return
+	}
+	s.busy = "scanning"
+
+	go func() {
+		ctx := s.ctx
+		if s.c.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.c.timeout)
+			defer cancel()
+		}
+
+		start := time.Now()
+		results, err := cleaner.NewScanner(s.c.Options).Scan(ctx)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.c.scanDuration = time.Since(start)
+		if err == nil {
+			s.c.recordScan(results)
+			s.c.writeMetrics(results, nil)
+		}
+		s.busy = ""
+		s.results, s.scanErr, s.scannedAt, s.deleted = results, err, time.Now(), nil
+	}()
+
+	writeServeJSON(w, http.StatusAccepted, serveStatus{Status: s.busy})
+}
+
+func (s *server) status(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := serveStatus{Status: s.busy}
+	if st.Status == "" {
+		st.Status = "idle"
+	}
+	if !s.scannedAt.IsZero() {
+		scannedAt := s.scannedAt
+		st.ScannedAt = &scannedAt
+	}
+	if s.scanErr != nil {
+		st.Error = s.scanErr.Error()
+	}
+	if s.results != nil {
+		st.Results = newJSONReport(s.results)
+	}
+	writeServeJSON(w, http.StatusOK, st)
+}
+
+func (s *server) delete(w http.ResponseWriter, r *http.Request) {
+	var req deleteRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if s.busy != "" {
+		s.mu.Unlock()
+		http.Error(w, "already "+s.busy, http.StatusConflict)
+		return
+	}
+	if s.results == nil {
+		s.mu.Unlock()
+		http.Error(w, "no scan to delete from, POST /scan first", http.StatusConflict)
+		return
+	}
+	if s.scanErr != nil {
+		s.mu.Unlock()
+		http.Error(w, "the last scan didn't finish, nothing deleted, POST /scan again", http.StatusConflict)
+		return
+	}
+
+	found := make(map[string]*cleaner.Folder, len(s.results.Folders))
+	for _, f := range s.results.Folders {
+		found[f.Path] = f
+	}
+	chosen := make([]*cleaner.Folder, 0, len(req.Paths))
+	seen := make(map[string]bool, len(req.Paths))
+	for _, p := range req.Paths {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		f, ok := found[p]
+		if !ok {
+			s.mu.Unlock()
+			http.Error(w, fmt.Sprintf("%s was not found by the last scan", p), http.StatusBadRequest)
+			return
+		}
+		chosen = append(chosen, f)
+	}
+	s.busy = "deleting"
+	results := s.results
+	s.mu.Unlock()
+
+	selected := &cleaner.Result{Folders: chosen}
+	s.c.recordRestore(selected)
+	deleted := cleaner.Delete(s.ctx, selected, s.c.Options, nil)
+	s.c.recordHistory(selected, deleted)
+
+	s.mu.Lock()
+	s.c.writeMetrics(results, deleted)
+	s.busy = ""
+	s.deleted = append(s.deleted, deleted...)
+	results.Keep(notDeleted(results.Folders, successful(deleted)))
+	s.mu.Unlock()
+
+	report := newJSONReport(nil)
+	report.addDeleted(deleted)
+	writeServeJSON(w, http.StatusOK, report)
+}
+
+func (s *server) metrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.results == nil {
+		http.Error(w, "no scan yet, POST /scan first", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	newRunMetrics(s.results, s.c.scanDuration, s.deleted).write(w)
+}
+
+// successful returns the results in deleted that didn't fail.
+func successful(deleted []cleaner.DeleteResult) []cleaner.DeleteResult {
+	var ok []cleaner.DeleteResult
+	for _, r := range deleted {
+		if r.Err == nil {
+			ok = append(ok, r)
+		}
+	}
+	return ok
+}
+
+func writeServeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
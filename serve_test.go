@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+const testToken = "s3cret"
+
+// serveRequest sends a request with the test token to h and returns the
+// response.
+func serveRequest(h http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestServeDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup, if set, changes the server before the request.
+		setup func(s *server)
+		// paths are the projects whose node_modules folders to delete.
+		paths    []string
+		wantCode int
+		wantLeft []string
+	}{
+		{
+			name:     "deletes the folders asked for",
+			paths:    []string{"a"},
+			wantCode: http.StatusOK,
+			wantLeft: []string{"b"},
+		},
+		{
+			name:     "a folder listed twice",
+			paths:    []string{"a", "a"},
+			wantCode: http.StatusOK,
+			wantLeft: []string{"b"},
+		},
+		{
+			name:     "not found by the last scan",
+			paths:    []string{"a", "c"},
+			wantCode: http.StatusBadRequest,
+			wantLeft: []string{"a", "b"},
+		},
+		{
+			name:     "the last scan didn't finish",
+			setup:    func(s *server) { s.scanErr = context.DeadlineExceeded },
+			paths:    []string{"a"},
+			wantCode: http.StatusConflict,
+			wantLeft: []string{"a", "b"},
+		},
+		{
+			name:     "no scan yet",
+			setup:    func(s *server) { s.results = nil },
+			paths:    []string{"a"},
+			wantCode: http.StatusConflict,
+			wantLeft: []string{"a", "b"},
+		},
+		{
+			name:     "scanning",
+			setup:    func(s *server) { s.busy = "scanning" },
+			paths:    []string{"a"},
+			wantCode: http.StatusConflict,
+			wantLeft: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, results := projects(t, "a", "b")
+			c := testConfig(root)
+			c.token = testToken
+			s := &server{c: c, ctx: context.Background(), results: results}
+			if tt.setup != nil {
+				tt.setup(s)
+			}
+
+			paths := make([]string, len(tt.paths))
+			for i, name := range tt.paths {
+				paths[i] = filepath.Join(root, name, cleaner.NodeModules)
+			}
+			body, err := json.Marshal(deleteRequest{Paths: paths})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := serveRequest(s.handle(http.MethodPost, s.delete), http.MethodPost, string(body))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if left := remaining(t, root, "a", "b"); !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("left = %q, want %q", left, tt.wantLeft)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var report jsonReport
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Deleted) != 1 || !report.Deleted[0].Deleted {
+				t.Errorf("deleted = %+v, want the one folder", report.Deleted)
+			}
+			if len(s.deleted) != 1 {
+				t.Errorf("server recorded %d deletions, want 1", len(s.deleted))
+			}
+			if len(s.results.Folders) != 1 {
+				t.Errorf("%d folders left to delete, want 1", len(s.results.Folders))
+			}
+		})
+	}
+}
+
+func TestServeAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     string
+		method   string
+		wantCode int
+	}{
+		{name: "bearer token", auth: "Bearer " + testToken, method: http.MethodGet, wantCode: http.StatusOK},
+		{name: "no header", method: http.MethodGet, wantCode: http.StatusUnauthorized},
+		{name: "wrong token", auth: "Bearer wrong", method: http.MethodGet, wantCode: http.StatusUnauthorized},
+		{name: "token without Bearer", auth: testToken, method: http.MethodGet, wantCode: http.StatusUnauthorized},
+		{name: "other scheme", auth: "Basic " + testToken, method: http.MethodGet, wantCode: http.StatusUnauthorized},
+		{name: "empty bearer token", auth: "Bearer ", method: http.MethodGet, wantCode: http.StatusUnauthorized},
+		{name: "wrong method", auth: "Bearer " + testToken, method: http.MethodPost, wantCode: http.StatusMethodNotAllowed},
+		{name: "wrong method without token", method: http.MethodPost, wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig()
+			c.token = testToken
+			s := &server{c: c, ctx: context.Background()}
+
+			r := httptest.NewRequest(tt.method, "/results", nil)
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			s.handle(http.MethodGet, s.status)(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeStatusPartialScan(t *testing.T) {
+	_, results := projects(t, "a")
+	c := newConfig()
+	c.token = testToken
+	s := &server{c: c, ctx: context.Background(), results: results, scanErr: context.DeadlineExceeded, scannedAt: time.Now()}
+
+	w := serveRequest(s.handle(http.MethodGet, s.status), http.MethodGet, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var st serveStatus
+	if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != "idle" || st.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("status %q, error %q, want idle with the scan's error", st.Status, st.Error)
+	}
+	if st.Results == nil || len(st.Results.Folders) != 1 {
+		t.Errorf("results = %+v, want the one folder found so far", st.Results)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// scansFile holds a summary of every full scan, next to the history file.
+const scansFile = "scans.jsonl"
+
+// scanRecord is one scan, stored as a line of JSON in the scans file.
+type scanRecord struct {
+	Time    time.Time    `json:"time"`
+	From    []string     `json:"from"`
+	Folders []scanFolder `json:"folders"`
+}
+
+type scanFolder struct {
+	Path       string `json:"path"`
+	SizeBytes  int64  `json:"sizeBytes"`
+	ModDaysAgo int    `json:"modDaysAgo"`
+}
+
+func scansFilePath() (string, error) {
+	p, err := historyFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), scansFile), nil
+}
+
+// recordScan appends every folder the scan found, including those for review
+// or with uncommitted changes, to the scans file for the stats command. Only
+// scans from the start folders are recorded, as folders looked at directly
+// say nothing about the footprint as a whole.
+func (c *Config) recordScan(results *cleaner.Result) {
+	if !c.history || c.Paths != nil || c.Histogram || c.Caches || c.command == "analyze" {
+		return
+	}
+
+	rec := scanRecord{Time: time.Now(), From: c.fromDirs.values(), Folders: make([]scanFolder, 0)}
+	for _, folders := range [][]*cleaner.Folder{results.Folders, results.Review, results.Dirty} {
+		for _, f := range folders {
+			rec.Folders = append(rec.Folders, scanFolder{Path: f.Path, SizeBytes: f.SizeBytes, ModDaysAgo: f.ModDaysAgo})
+		}
+	}
+
+	p, err := scansFilePath()
+	if err == nil {
+		err = appendJSONLine(p, rec)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: recording scan: %s\n", err)
+	}
+}
+
+func readScans(since time.Time) ([]scanRecord, error) {
+	p, err := scansFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var scans []scanRecord
+	err = readJSONLines(p, func(line []byte) {
+		var rec scanRecord
+		if json.Unmarshal(line, &rec) == nil && !rec.Time.Before(since) {
+			scans = append(scans, rec)
+		}
+	})
+	return scans, err
+}
+
+// footprint is the total found by one scan.
+type footprint struct {
+	Time          time.Time `json:"time"`
+	From          []string  `json:"from"`
+	Folders       int       `json:"folders"`
+	SizeBytes     int64     `json:"sizeBytes"`
+	AvgModDaysAgo int       `json:"avgModDaysAgo"`
+}
+
+// offender is a folder that has been deleted more than once, as its project
+// keeps reinstalling it.
+type offender struct {
+	Path       string `json:"path"`
+	Times      int    `json:"times"`
+	BytesFreed int64  `json:"bytesFreed"`
+}
+
+type stats struct {
+	Footprint []footprint `json:"footprint"`
+	Offenders []offender  `json:"offenders"`
+}
+
+// maxOffenders is how many repeat offenders stats lists.
+const maxOffenders = 10
+
+func newStats(scans []scanRecord, runs []historyRun) *stats {
+	s := &stats{Footprint: make([]footprint, 0), Offenders: make([]offender, 0)}
+
+	for _, rec := range scans {
+		fp := footprint{Time: rec.Time, From: rec.From, Folders: len(rec.Folders)}
+		days := 0
+		for _, f := range rec.Folders {
+			fp.SizeBytes += f.SizeBytes
+			days += f.ModDaysAgo
+		}
+		if len(rec.Folders) > 0 {
+			fp.AvgModDaysAgo = days / len(rec.Folders)
+		}
+		s.Footprint = append(s.Footprint, fp)
+	}
+
+	byPath := make(map[string]*offender)
+	for _, run := range runs {
+		for _, f := range run.Folders {
+			o := byPath[f.Path]
+			if o == nil {
+				o = &offender{Path: f.Path}
+				byPath[f.Path] = o
+			}
+			o.Times++
+			o.BytesFreed += f.BytesFreed
+		}
+	}
+	for _, o := range byPath {
+		if o.Times > 1 {
+			s.Offenders = append(s.Offenders, *o)
+		}
+	}
+	sort.Slice(s.Offenders, func(i, j int) bool {
+		if s.Offenders[i].BytesFreed != s.Offenders[j].BytesFreed {
+			return s.Offenders[i].BytesFreed > s.Offenders[j].BytesFreed
+		}
+		return s.Offenders[i].Path < s.Offenders[j].Path
+	})
+	if len(s.Offenders) > maxOffenders {
+		s.Offenders = s.Offenders[:maxOffenders]
+	}
+
+	return s
+}
+
+// runStats prints how the space taken by the folders found has changed from
+// scan to scan, and the folders deleted most often.
+func runStats(out io.Writer, c *Config) error {
+	var since time.Time
+	if c.historySince > 0 {
+		since = time.Now().Add(-c.historySince)
+	}
+
+	scans, err := readScans(since)
+	if err != nil {
+		return err
+	}
+	runs, err := readHistory(since)
+	if err != nil {
+		return err
+	}
+	s := newStats(scans, runs)
+
+	if c.json {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	if len(s.Footprint) == 0 {
+		_, _ = fmt.Fprintf(out, "No scans recorded\n")
+	} else {
+		_, _ = fmt.Fprintf(out, "Found by each scan:\n")
+		_, _ = fmt.Fprintf(out, "%-17s|%8s|%12s|%20s| %s\n", "Date", "Folders", "Size", "Avg Modified Days", "From")
+		for _, fp := range s.Footprint {
+			_, _ = fmt.Fprintf(out, "%-17s|%8d|%12s|%20d| %s\n", fp.Time.Local().Format("2006-01-02 15:04"),
+				fp.Folders, cleaner.FormatSize(fp.SizeBytes), fp.AvgModDaysAgo, strings.Join(fp.From, ", "))
+		}
+
+		// Only scans of the same folders as the latest are comparable.
+		last := s.Footprint[len(s.Footprint)-1]
+		first := last
+		for _, fp := range s.Footprint {
+			if strings.Join(fp.From, "\n") == strings.Join(last.From, "\n") {
+				first = fp
+				break
+			}
+		}
+		if !first.Time.Equal(last.Time) {
+			change := last.SizeBytes - first.SizeBytes
+			sign := "+"
+			if change < 0 {
+				sign, change = "-", -change
+			}
+			_, _ = fmt.Fprintf(out, "\n%s%s in %s since %s\n", sign, cleaner.FormatSize(change), strings.Join(last.From, ", "), first.Time.Local().Format("2006-01-02"))
+		}
+	}
+
+	if len(s.Offenders) > 0 {
+		longestPath := 0
+		for _, o := range s.Offenders {
+			if len(o.Path) > longestPath {
+				longestPath = len(o.Path)
+			}
+		}
+
+		_, _ = fmt.Fprintf(out, "\nDeleted most often:\n")
+		_, _ = fmt.Fprintf(out, "%-*s|%8s|%12s\n", longestPath+1, "Path", "Times", "Freed")
+		for _, o := range s.Offenders {
+			_, _ = fmt.Fprintf(out, "%-*s|%8d|%12s\n", longestPath+1, o.Path, o.Times, cleaner.FormatSize(o.BytesFreed))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// readPaths reads the folders given to -stdin from r. They are separated by
+// NUL bytes if there are any, as from find -print0 or fd -0, otherwise one to
+// a line. Blank entries are ignored, and the result is never nil so that an
+// empty list means nothing to look at rather than scan from -from.
+func readPaths(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "\n"
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = "\x00"
+	}
+
+	paths := []string{}
+	for _, p := range strings.Split(string(data), sep) {
+		p = strings.TrimSuffix(p, "\r")
+		if strings.TrimSpace(p) != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
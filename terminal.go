@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+func validColor(mode string) bool {
+	switch mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return true
+	}
+	return false
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+const (
+	PathsFull     = "full"
+	PathsRelative = "relative"
+	PathsHome     = "home"
+)
+
+func validPathDisplay(paths string) bool {
+	switch paths {
+	case PathsFull, PathsRelative, PathsHome:
+		return true
+	}
+	return false
+}
+
+// minPathWidth is the narrowest the path column is truncated to, however
+// narrow the terminal.
+const minPathWidth = 24
+
+// tableStyle is how tables are printed to the terminal: in color or not, and
+// fitted to its width, 0 if it isn't one.
+type tableStyle struct {
+	color bool
+	width int
+	// paths is how paths are shown, e.g. PathsRelative to the deepest of
+	// roots holding them.
+	paths string
+	roots []string
+}
+
+// newTableStyle works out the style for tables printed to out. With mode
+// ColorAuto there is color only if out is a terminal, NO_COLOR isn't set and
+// TERM isn't dumb. Tables are fitted to the terminal's width, or to COLUMNS
+// if that is set.
+func newTableStyle(out *os.File, mode string) *tableStyle {
+	terminal := isInteractive(out)
+	s := &tableStyle{}
+	switch mode {
+	case ColorAlways:
+		s.color = true
+	case ColorAuto:
+		_, noColor := os.LookupEnv("NO_COLOR")
+		s.color = terminal && !noColor && os.Getenv("TERM") != "dumb" && enableColor(out)
+	}
+
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		s.width = columns
+	} else if terminal {
+		s.width = terminalWidth(out)
+	}
+	return s
+}
+
+// paint wraps s in the ANSI codes given, if the style has color.
+func (s *tableStyle) paint(text string, codes ...string) string {
+	if s == nil || !s.color || len(codes) == 0 {
+		return text
+	}
+	prefix := ""
+	for _, c := range codes {
+		prefix += c
+	}
+	return prefix + text + ansiReset
+}
+
+// sizeColor is the color for a folder of size bytes, hotter for larger.
+func sizeColor(size int64) []string {
+	switch {
+	case size >= cleaner.GB:
+		return []string{ansiBold, ansiRed}
+	case size >= 100*cleaner.MB:
+		return []string{ansiYellow}
+	}
+	return []string{ansiGreen}
+}
+
+// pathWidth returns how wide the path column can be for a table whose other
+// columns take rest, given that the longest path is longest.
+func (s *tableStyle) pathWidth(longest, rest int) int {
+	if s == nil || s.width == 0 || longest+rest <= s.width {
+		return longest
+	}
+	if w := s.width - rest; w > minPathWidth {
+		return w
+	}
+	return minPathWidth
+}
+
+// shorten returns p as the style shows paths: in full, relative to its start
+// folder, or with the home folder as ~. A path it can't shorten is shown in
+// full.
+func (s *tableStyle) shorten(p string) string {
+	if s == nil {
+		return p
+	}
+
+	switch s.paths {
+	case PathsRelative:
+		if root := startDirOf(s.roots, p); root != "" {
+			if rel, err := filepath.Rel(root, p); err == nil {
+				return rel
+			}
+		}
+	case PathsHome:
+		if home, err := os.UserHomeDir(); err == nil && isBelow(home, p) {
+			if rel, err := filepath.Rel(home, p); err == nil {
+				return filepath.Join("~", rel)
+			}
+		}
+	}
+	return p
+}
+
+// truncateMiddle shortens p to width by replacing its middle with "...",
+// keeping the start of the path and the folder name at its end.
+func truncateMiddle(p string, width int) string {
+	r := []rune(p)
+	if len(r) <= width || width < 5 {
+		return p
+	}
+	keep := width - 3
+	head := keep / 2
+	tail := keep - head
+	return string(r[:head]) + "..." + string(r[len(r)-tail:])
+}
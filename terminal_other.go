@@ -0,0 +1,14 @@
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package main
+
+import "os"
+
+// terminalWidth returns 0, as the width can't be told here.
+func terminalWidth(f *os.File) int {
+	return 0
+}
+
+func enableColor(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var (
+	procGetConsoleScreenBufferInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleScreenBufferInfo")
+	procGetConsoleMode             = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleMode")
+	procSetConsoleMode             = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+)
+
+type consoleScreenBufferInfo struct {
+	sizeX, sizeY                           int16
+	cursorX, cursorY                       int16
+	attributes                             uint16
+	left, top, right, bottom               int16
+	maximumWindowSizeX, maximumWindowSizeY int16
+}
+
+// terminalWidth returns the width of the console window f is, or 0 if it
+// isn't one.
+func terminalWidth(f *os.File) int {
+	var info consoleScreenBufferInfo
+	ok, _, _ := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 0
+	}
+	return int(info.right-info.left) + 1
+}
+
+// enableColor turns on ANSI escape codes for the console f is, reporting
+// whether it could, as consoles before Windows 10 don't support them.
+func enableColor(f *os.File) bool {
+	var mode uint32
+	if ok, _, _ := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&mode))); ok == 0 {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := procSetConsoleMode.Call(f.Fd(), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
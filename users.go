@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// userSummaryFile is written to each user's home folder with -notify-users.
+const userSummaryFile = "npm-cleaner-summary.txt"
+
+// foldersBelow returns the folders in folders below dir.
+func foldersBelow(folders []*cleaner.Folder, dir string) []*cleaner.Folder {
+	var below []*cleaner.Folder
+	for _, f := range folders {
+		if isBelow(dir, f.Path) {
+			below = append(below, f)
+		}
+	}
+	return below
+}
+
+// writeUserSummaries writes each user with folders found or deleted a summary
+// of their own in their home folder, for -notify-users. Failing to write one
+// is only a warning.
+func (c *Config) writeUserSummaries(results *cleaner.Result, deleted []cleaner.DeleteResult) {
+	host, _ := os.Hostname()
+	for _, home := range c.userHomes {
+		user := &cleaner.Result{Folders: foldersBelow(results.Folders, home)}
+		for _, f := range user.Folders {
+			user.TotalSize += f.SizeBytes
+		}
+
+		var userDeleted []cleaner.DeleteResult
+		if deleted != nil {
+			userDeleted = make([]cleaner.DeleteResult, 0)
+			for _, r := range deleted {
+				if isBelow(home, r.Path) {
+					userDeleted = append(userDeleted, r)
+				}
+			}
+		}
+		if len(user.Folders) == 0 && len(userDeleted) == 0 {
+			continue
+		}
+
+		var b strings.Builder
+		_, _ = fmt.Fprintf(&b, "npm-cleaner on %s, %s:\n%s\n\n", host, time.Now().Format("2006-01-02 15:04"), runSummary(user, userDeleted))
+		if deleted == nil {
+			for _, f := range user.Folders {
+				_, _ = fmt.Fprintf(&b, "  %12s  %s\n", cleaner.FormatSize(f.SizeBytes), f.Path)
+			}
+			_, _ = fmt.Fprintf(&b, "\nThese folders may be deleted to free space on this machine. Add a %s file to a project to keep its folders.\n", cleaner.KeepMarker)
+		} else {
+			for _, r := range userDeleted {
+				if r.Err == nil {
+					_, _ = fmt.Fprintf(&b, "  %12s  %s\n", cleaner.FormatSize(r.BytesFreed), r.Path)
+				}
+			}
+			_, _ = fmt.Fprintf(&b, "\nReinstall a project's dependencies with its package manager, e.g. npm install, when you next work on it.\n")
+		}
+
+		if err := writeUserFile(filepath.Join(home, userSummaryFile), home, b.String()); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: writing the summary for %s: %s\n", filepath.Base(home), err)
+		}
+	}
+}
+
+// writeUserFile writes content to a new file at p, owned by whoever owns
+// home. Anything already at p is replaced rather than written through, as a
+// user could have put a link there to a file only root may write.
+func writeUserFile(p, home, content string) error {
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := chownLike(f, home); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
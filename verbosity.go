@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// How much is printed about a run, besides its results.
+const (
+	// VerbosityQuiet prints only the results, without progress, warnings or
+	// hints.
+	VerbosityQuiet = "quiet"
+	// VerbosityNormal also shows progress, warnings and hints.
+	VerbosityNormal = "normal"
+	// VerbosityVerbose also prints why each folder is skipped, as it is.
+	VerbosityVerbose = "verbose"
+	// VerbosityTrace also prints every folder visited, found and sized.
+	VerbosityTrace = "trace"
+)
+
+func validVerbosity(level string) bool {
+	switch level {
+	case VerbosityQuiet, VerbosityNormal, VerbosityVerbose, VerbosityTrace:
+		return true
+	}
+	return false
+}
+
+// levelFlag is a boolean flag, such as -v, that sets the verbosity to level.
+type levelFlag struct {
+	verbosity *string
+	level     string
+}
+
+func (l *levelFlag) IsBoolFlag() bool { return true }
+
+func (l *levelFlag) String() string {
+	if l == nil || l.verbosity == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*l.verbosity == l.level)
+}
+
+func (l *levelFlag) Set(v string) error {
+	on, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	if on {
+		*l.verbosity = l.level
+	} else if *l.verbosity == l.level {
+		*l.verbosity = VerbosityNormal
+	}
+	return nil
+}
+
+// quiet reports whether only the results are printed.
+func (c *Config) quiet() bool {
+	return c.verbosity == VerbosityQuiet
+}
+
+// logEvents returns a function printing scan events to stderr as they happen,
+// above the progress line, for -v and -vv. It returns nil if nothing would be
+// printed, so the scan doesn't report every folder for nothing.
+func (c *Config) logEvents(p *progress) func(e cleaner.Event) {
+	trace := c.verbosity == VerbosityTrace
+	if !trace && c.verbosity != VerbosityVerbose {
+		return nil
+	}
+
+	return func(e cleaner.Event) {
+		switch {
+		case e.Kind == cleaner.FolderSkipped, e.Kind == cleaner.FolderVisited && e.Reason != "":
+			p.printf("skipped %s: %s\n", e.Folder.Path, e.Reason)
+		case !trace:
+		case e.Kind == cleaner.FolderVisited:
+			p.printf("visiting %s\n", e.Folder.Path)
+		case e.Kind == cleaner.FolderFound:
+			p.printf("found %s\n", e.Folder.Path)
+		case e.Kind == cleaner.SizeComputed:
+			p.printf("sized %s: %s\n", e.Folder.Path, cleaner.FormatSize(e.Folder.SizeBytes))
+		case e.Kind == cleaner.Error:
+			p.printf("error %s: %s\n", e.Folder.Path, e.Err)
+		}
+	}
+}
+
+// warn prints err as a warning, unless only the results are printed.
+func (c *Config) warn(p *progress, err error) {
+	c.runLog.record("warning", "error", err.Error())
+	if !c.quiet() {
+		p.printf("warning: %s\n", err)
+	}
+}
+
+// printHint prints a hint about what to do next, unless only the results are
+// printed.
+func (c *Config) printHint(format string, args ...interface{}) {
+	if !c.quiet() {
+		fmt.Printf(format, args...)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"npm-cleaner/pkg/cleaner"
+)
+
+// watch scans c.FromDir to build the index and then keeps it up to date until
+// interrupted.
+func watch(ctx context.Context, c *Config) error {
+	w, err := cleaner.NewWatcher(ctx, c.Options)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Watching %d folders under %s, press Ctrl-C to stop\n", w.Len(), c.FromDir)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	return w.Run(ctx)
+}